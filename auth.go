@@ -0,0 +1,60 @@
+package signalr
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// AccessTokenProvider supplies the bearer token used to authenticate with the signalr peer.  It is invoked before every
+// negotiate, connect and reconnect request, so implementations are free to refresh expiring tokens as needed.
+type AccessTokenProvider func(ctx context.Context) (string, error)
+
+// AccessTokenPlacement where the token from the AccessTokenProvider is applied to outgoing requests.
+type AccessTokenPlacement int
+
+// Access token placement values
+const (
+	//AccessTokenHeader sends the token as an "Authorization: Bearer" header.  The default.
+	AccessTokenHeader AccessTokenPlacement = iota
+	//AccessTokenQuery sends the token as the "access_token" query parameter, as browser-oriented servers expect for websockets.
+	AccessTokenQuery
+)
+
+const accessTokenQueryKey string = "access_token"
+
+// requestHeader returns a copy of the configured request headers, safe to modify per request.
+func (c *client) requestHeader() http.Header {
+	if c.config.RequestHeaders == nil {
+		return http.Header{}
+	}
+
+	return c.config.RequestHeaders.Clone()
+}
+
+// authorize fetches a fresh access token and applies it to the outgoing header or query.
+// noop if no AccessTokenProvider is configured.
+func (c *client) authorize(ctx context.Context, header http.Header, query url.Values) error {
+	if c.config.AccessTokenProvider == nil {
+		return nil
+	}
+
+	token, err := c.config.AccessTokenProvider(ctx)
+	if err != nil {
+		return newAccessTokenError("Unable to retrieve access token", err)
+	}
+
+	switch c.config.AccessTokenPlacement {
+	case AccessTokenQuery:
+		query.Set(accessTokenQueryKey, token)
+	default:
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// isUnauthorized true if the peer rejected the request's credentials.
+func isUnauthorized(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusUnauthorized
+}
@@ -0,0 +1,158 @@
+package signalr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAuthorizePlacement(t *testing.T) {
+	provider := func(ctx context.Context) (string, error) {
+		return "t0k3n", nil
+	}
+
+	//header placement
+	c := New(Config{AccessTokenProvider: provider}).(*client)
+	header, query := c.requestHeader(), url.Values{}
+
+	if err := c.authorize(context.Background(), header, query); err != nil {
+		t.Fatalf("unexpected authorize error: %v", err)
+	}
+
+	if got := header.Get("Authorization"); got != "Bearer t0k3n" {
+		t.Errorf("expected bearer header, found %q", got)
+	}
+
+	if query.Get(accessTokenQueryKey) != "" {
+		t.Errorf("token unexpectedly placed in query: %+v", query)
+	}
+
+	//query placement
+	c = New(Config{AccessTokenProvider: provider, AccessTokenPlacement: AccessTokenQuery}).(*client)
+	header, query = c.requestHeader(), url.Values{}
+
+	if err := c.authorize(context.Background(), header, query); err != nil {
+		t.Fatalf("unexpected authorize error: %v", err)
+	}
+
+	if got := query.Get(accessTokenQueryKey); got != "t0k3n" {
+		t.Errorf("expected token in query, found %q", got)
+	}
+
+	if header.Get("Authorization") != "" {
+		t.Errorf("token unexpectedly placed in header: %+v", header)
+	}
+}
+
+func TestAuthorizeProviderError(t *testing.T) {
+	c := New(Config{
+		AccessTokenProvider: func(ctx context.Context) (string, error) {
+			return "", errors.New("no token for you")
+		},
+	}).(*client)
+
+	err := c.authorize(context.Background(), http.Header{}, url.Values{})
+
+	if _, ok := err.(AccessTokenError); !ok {
+		t.Errorf("expected AccessTokenError, received %T", err)
+	}
+}
+
+func TestAuthorizeDoesNotMutateConfigHeaders(t *testing.T) {
+	c := New(Config{
+		RequestHeaders: http.Header{"X-Static": []string{"1"}},
+		AccessTokenProvider: func(ctx context.Context) (string, error) {
+			return "t0k3n", nil
+		},
+	}).(*client)
+
+	if err := c.authorize(context.Background(), c.requestHeader(), url.Values{}); err != nil {
+		t.Fatalf("unexpected authorize error: %v", err)
+	}
+
+	if c.config.RequestHeaders.Get("Authorization") != "" {
+		t.Errorf("configured request headers mutated: %+v", c.config.RequestHeaders)
+	}
+}
+
+func TestNegotiateRefreshesTokenOnUnauthorized(t *testing.T) {
+	//Assemble
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ConnectionToken":"abc","ProtocolVersion":"1.5","KeepAliveTimeout":20}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	tokens := []string{"stale", "fresh"}
+	calls := 0
+
+	c := New(Config{
+		Client:        server.Client(),
+		ConnectionURL: serverURL,
+		AccessTokenProvider: func(ctx context.Context) (string, error) {
+			token := tokens[calls]
+			calls++
+			return token, nil
+		},
+	}).(*client)
+
+	//Act
+	nresp, err := c.negotiate()
+
+	//Assert
+	if err != nil {
+		t.Fatalf("negotiate failed after refresh: %v", err)
+	}
+
+	if nresp.ConnectionToken != "abc" {
+		t.Errorf("unexpected connection token %q", nresp.ConnectionToken)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected provider to be called twice, called %d times", calls)
+	}
+}
+
+func TestNegotiateFailsAfterSecondUnauthorized(t *testing.T) {
+	//Assemble
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	calls := 0
+
+	c := New(Config{
+		Client:        server.Client(),
+		ConnectionURL: serverURL,
+		AccessTokenProvider: func(ctx context.Context) (string, error) {
+			calls++
+			return "stale", nil
+		},
+	}).(*client)
+	c.setState(Connecting)
+
+	//Act
+	_, err := c.negotiate()
+
+	//Assert
+	if _, ok := err.(AccessTokenError); !ok {
+		t.Errorf("expected AccessTokenError, received %T", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected exactly one refresh, provider called %d times", calls)
+	}
+
+	if c.State() != Broken {
+		t.Errorf("expected client to be broken, found %d", c.State())
+	}
+}
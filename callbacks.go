@@ -0,0 +1,201 @@
+package signalr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// closedEvent internal closed feed event.  wraps the error so a clean close can still be published.
+type closedEvent struct {
+	err error
+}
+
+// dispatcher runs callbacks one at a time, in order, on a goroutine of its own so a slow or buggy handler can't stall
+// the read loop.  the goroutine exits whenever the queue drains and is restarted on demand.  at most limit callbacks
+// wait; the rest are dropped, so a stuck handler costs events rather than memory.
+type dispatcher struct {
+	mutex   sync.Mutex
+	queue   []func()
+	running bool
+	limit   int
+	onPanic func(callback string, recovered interface{})
+	onDrop  func(callback string)
+}
+
+func (d *dispatcher) enqueue(callback string, fn func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.limit > 0 && len(d.queue) >= d.limit {
+		if d.onDrop != nil {
+			d.onDrop(callback)
+		}
+		return
+	}
+
+	d.queue = append(d.queue, func() { d.invoke(callback, fn) })
+
+	if !d.running {
+		d.running = true
+		go d.run()
+	}
+}
+
+func (d *dispatcher) run() {
+	for {
+		d.mutex.Lock()
+		if len(d.queue) == 0 {
+			d.running = false
+			d.mutex.Unlock()
+			return
+		}
+
+		fn := d.queue[0]
+		d.queue[0] = nil
+		d.queue = d.queue[1:]
+		d.mutex.Unlock()
+
+		fn()
+	}
+}
+
+func (d *dispatcher) invoke(callback string, fn func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil && d.onPanic != nil {
+			d.onPanic(callback, recovered)
+		}
+	}()
+
+	fn()
+}
+
+// reportCallbackPanic surface a recovered handler panic on the error feed.
+func (c *client) reportCallbackPanic(callback string, recovered interface{}) {
+	c.sendErr(CallbackPanicError{
+		Callback:  callback,
+		Recovered: fmt.Sprintf("%v", recovered),
+	})
+}
+
+// reportDroppedCallback count a callback discarded because the dispatcher queue was full.  not sent on the error feed,
+// where it would only queue another callback.
+func (c *client) reportDroppedCallback(callback string) {
+	atomic.AddUint64(&c.dropped.callbacks, 1)
+	c.countDrop("callbacks")
+}
+
+// onStateChange register fn for state transitions matching filter.
+func (c *client) onStateChange(callback string, filter func(StateChange) bool, fn func(StateChange)) UnsubscribeFunc {
+	return c.stateFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			change := event.(StateChange)
+			if filter(change) {
+				c.callbacks.enqueue(callback, func() { fn(change) })
+			}
+		},
+		func() {},
+	)
+}
+
+// OnStateChanged register a handler for every state change.
+func (c *client) OnStateChanged(handler func(old ConnectionState, new ConnectionState)) UnsubscribeFunc {
+	return c.onStateChange(
+		"OnStateChanged",
+		func(StateChange) bool { return true },
+		func(st StateChange) { handler(st.From, st.To) },
+	)
+}
+
+// OnReconnecting register a handler for when the client starts reconnecting a dropped socket.
+func (c *client) OnReconnecting(handler func()) UnsubscribeFunc {
+	return c.onStateChange(
+		"OnReconnecting",
+		func(st StateChange) bool { return st.To == Reconnecting },
+		func(StateChange) { handler() },
+	)
+}
+
+// OnReconnected register a handler for when a reconnect succeeds.
+func (c *client) OnReconnected(handler func()) UnsubscribeFunc {
+	return c.onStateChange(
+		"OnReconnected",
+		func(st StateChange) bool { return st.From == Reconnecting && st.To == Connected },
+		func(StateChange) { handler() },
+	)
+}
+
+// OnClosed register a handler for when Connect returns, with the error that ended the connection.
+func (c *client) OnClosed(handler func(error)) UnsubscribeFunc {
+	return c.closedFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			closed := event.(closedEvent)
+			c.callbacks.enqueue("OnClosed", func() { handler(closed.err) })
+		},
+		func() {},
+	)
+}
+
+// OnError register a handler for every error the lib or signalr peer generates.
+func (c *client) OnError(handler func(error)) UnsubscribeFunc {
+	return c.errFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			err := event.(error)
+
+			// a panicking error handler would otherwise be fed its own panic forever.
+			if cpe, ok := err.(CallbackPanicError); ok && cpe.Callback == "OnError" {
+				return
+			}
+
+			c.callbacks.enqueue("OnError", func() { handler(err) })
+		},
+		func() {},
+	)
+}
+
+// OnReceived register a handler for every raw frame received from the signalr peer, before any parsing.
+func (c *client) OnReceived(handler func(json.RawMessage)) UnsubscribeFunc {
+	return c.receivedFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			raw := event.(json.RawMessage)
+			c.callbacks.enqueue("OnReceived", func() { handler(raw) })
+		},
+		func() {},
+	)
+}
+
+// OnHeartbeat register a handler for every heartbeat.
+func (c *client) OnHeartbeat(handler func(HeartbeatEvent)) UnsubscribeFunc {
+	return c.heartbeatFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			hb := event.(HeartbeatEvent)
+			c.callbacks.enqueue("OnHeartbeat", func() { handler(hb) })
+		},
+		func() {},
+	)
+}
+
+// OnConnectionSlow register a handler for when the peer has gone quiet for 2/3 of the keepalive timeout.
+func (c *client) OnConnectionSlow(handler func()) UnsubscribeFunc {
+	return c.errFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			if _, ok := event.(ConnectionSlow); ok {
+				c.callbacks.enqueue("OnConnectionSlow", handler)
+			}
+		},
+		func() {},
+	)
+}
+
+// OnGapDetected register a handler for when a reconnect falls back to a fresh connection and messages may have been lost.
+func (c *client) OnGapDetected(handler func(GapDetected)) UnsubscribeFunc {
+	return c.errFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			if gap, ok := event.(GapDetected); ok {
+				c.callbacks.enqueue("OnGapDetected", func() { handler(gap) })
+			}
+		},
+		func() {},
+	)
+}
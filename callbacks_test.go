@@ -0,0 +1,212 @@
+package signalr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOnStateChangedOrder(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+
+	type change struct{ from, to ConnectionState }
+	changes := make(chan change, 10)
+
+	c.OnStateChanged(func(old ConnectionState, new ConnectionState) {
+		changes <- change{old, new}
+	})
+
+	//Act
+	c.setState(Connecting)
+	c.setState(Connected)
+	c.setState(Reconnecting)
+
+	//Assert
+	expected := []change{{Ready, Connecting}, {Connecting, Connected}, {Connected, Reconnecting}}
+	for _, want := range expected {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("expected %+v, received %+v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %+v", want)
+		}
+	}
+}
+
+func TestOnReconnectingAndReconnected(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	events := make(chan string, 10)
+
+	c.OnReconnecting(func() { events <- "reconnecting" })
+	c.OnReconnected(func() { events <- "reconnected" })
+
+	//Act
+	c.setState(Connecting)
+	c.setState(Connected)
+	c.setState(Reconnecting)
+	c.setState(Connected)
+
+	//Assert
+	for _, want := range []string{"reconnecting", "reconnected"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("expected %s, received %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s", want)
+		}
+	}
+
+	select {
+	case extra := <-events:
+		t.Errorf("unexpected extra event %s", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCallbackPanicIsRecovered(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	errs := c.ListenToErrors()
+	received := make(chan json.RawMessage, 1)
+
+	c.OnReceived(func(json.RawMessage) { panic("buggy handler") })
+	c.OnReceived(func(raw json.RawMessage) { received <- raw })
+
+	//Act
+	c.receivedFeed.publish(json.RawMessage(`{}`))
+
+	//Assert
+	select {
+	case err := <-errs:
+		cpe, ok := err.(CallbackPanicError)
+		if !ok || cpe.Callback != "OnReceived" {
+			t.Errorf("expected CallbackPanicError from OnReceived, received %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for panic report")
+	}
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("a panicking handler should not stop the others")
+	}
+}
+
+func TestPanickingErrorHandlerDoesNotLoop(t *testing.T) {
+	c := New(Config{}).(*client)
+	calls := make(chan struct{}, 10)
+
+	c.OnError(func(error) {
+		calls <- struct{}{}
+		panic("error handler is broken too")
+	})
+
+	c.sendErr(TimeoutError("once"))
+
+	time.Sleep(100 * time.Millisecond)
+
+	if len(calls) != 1 {
+		t.Errorf("expected the error handler to run once, ran %d times", len(calls))
+	}
+}
+
+func TestSlowCallbackDoesNotBlock(t *testing.T) {
+	c := New(Config{}).(*client)
+	release := make(chan struct{})
+	defer close(release)
+
+	c.OnStateChanged(func(ConnectionState, ConnectionState) { <-release })
+	c.OnError(func(error) { <-release })
+	c.setState(Connecting)
+
+	finishesWithin(t, time.Second, "slow callbacks", func() {
+		for i := 0; i < 100; i++ {
+			c.setState(Disconnected)
+			c.setState(Connecting)
+			c.sendErr(TimeoutError("slow"))
+		}
+	})
+}
+
+func TestCallbackQueueIsBounded(t *testing.T) {
+	//Assemble
+	c := New(Config{CallbackBufferSize: 2}).(*client)
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	ran := make(chan ConnectionState, 10)
+
+	c.OnStateChanged(func(old ConnectionState, new ConnectionState) {
+		started <- struct{}{}
+		<-release
+		ran <- new
+	})
+
+	c.setState(Connecting)
+	<-started
+
+	//Act
+	for i := 0; i < 2; i++ {
+		c.setState(Disconnected)
+		c.setState(Connecting)
+	}
+	close(release)
+
+	//Assert
+	for _, want := range []ConnectionState{Connecting, Disconnected, Connecting} {
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Errorf("expected the change to %s, found %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for the change to %s", want)
+		}
+	}
+
+	select {
+	case extra := <-ran:
+		t.Errorf("expected the changes beyond the queue to be dropped, found %s", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if dropped := c.DroppedEvents().Callbacks; dropped != 2 {
+		t.Errorf("expected 2 dropped callbacks, found %d", dropped)
+	}
+}
+
+func TestOnClosedReceivesConnectError(t *testing.T) {
+	//Assemble
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+
+	closed := make(chan error, 1)
+	c.OnClosed(func(err error) { closed <- err })
+
+	//Act
+	connectErr := c.Connect([]string{"c2"})
+
+	//Assert
+	select {
+	case err := <-closed:
+		if err == nil || err != connectErr {
+			t.Errorf("expected OnClosed to receive %v, received %v", connectErr, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnClosed")
+	}
+}
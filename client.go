@@ -1,12 +1,16 @@
 package signalr
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -16,22 +20,15 @@ import (
 const (
 	defaultScheme string = "https"
 	socketScheme  string = "wss"
+	plainScheme   string = "http"
+	plainSocket   string = "ws"
 	negotiatePath string = "negotiate"
 	connectPath   string = "connect"
 	reconnectPath string = "reconnect"
-)
 
-//ConnectionState int representing current state of the SignalR Client
-type ConnectionState int
+	defaultEventBufferSize int = 5
 
-//SignalR Client State Values
-const (
-	Ready ConnectionState = iota
-	Connecting
-	Reconnecting
-	Connected
-	Disconnected
-	Broken
+	defaultCallbackBufferSize int = 1000
 )
 
 //Config define options required for connecting to a signalr endpoint.
@@ -42,25 +39,132 @@ type Config struct {
 	//URL for the signalr endpoint.  uses url.URL package to ensure valid url is used.
 	ConnectionURL *url.URL `json:"url"`
 
-	//URI path for negotiation portion of the connection.  Defaults to "/signalr/negotiate"
+	//URI path for negotiation portion of the connection, relative to the path of ConnectionURL.  Defaults to "negotiate"
 	NegotiatePath string `json:"negotiate_path,omitempty"`
 
-	//URI path for websocket connection.  Defaults to "/signalr/connect"
+	//URI path for websocket connection, relative to the path of ConnectionURL.  Defaults to "connect"
 	ConnectPath string `json:"connct_path,omitempty"`
 
-	//URI path for websocket reconnect.  Defaults to "/signalr/reconnect"
+	//URI path for websocket reconnect, relative to the path of ConnectionURL.  Defaults to "reconnect"
 	ReconnectPath string `json:"reconnect_path,omitempty"`
 
 	// RequestHeaders additional header parameters to add to the negotiation HTTP request.
 	RequestHeaders http.Header `json:"request_headers,omitempty"`
+
+	//TLSClientConfig client certificates, custom roots etc. for the websocket.  Defaults to the Client transport's TLS config.
+	TLSClientConfig *tls.Config `json:"-"`
+
+	//NetDialContext dial function for the websocket's TCP connection.  Defaults to the Client transport's DialContext.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error) `json:"-"`
+
+	//Proxy selects the proxy for the websocket.  Defaults to the Client transport's Proxy.
+	Proxy func(*http.Request) (*url.URL, error) `json:"-"`
+
+	//HandshakeTimeout websocket handshake timeout.  Defaults to 45 seconds for connect, 30 seconds for reconnect.
+	HandshakeTimeout time.Duration `json:"handshake_timeout,omitempty"`
+
+	//ReadBufferSize and WriteBufferSize websocket I/O buffer sizes.  Zero uses gorilla/websocket's defaults.
+	ReadBufferSize  int `json:"read_buffer_size,omitempty"`
+	WriteBufferSize int `json:"write_buffer_size,omitempty"`
+
+	//Subprotocols websocket subprotocols requested during the handshake.
+	Subprotocols []string `json:"subprotocols,omitempty"`
+
+	//EnableCompression offer permessage-deflate to the peer.  Off by default.
+	EnableCompression bool `json:"enable_compression,omitempty"`
+
+	//CompressionLevel flate level (-2 to 9) for outbound frames when compression is negotiated.  Zero keeps gorilla's default.
+	CompressionLevel int `json:"compression_level,omitempty"`
+
+	//ArgumentDecoders decoder chains applied to hub message arguments and CallHub results.  The first matching rule wins.
+	ArgumentDecoders []DecoderRule `json:"-"`
+
+	//MessageBufferSize capacity of the hub response channel.  Zero keeps it unbuffered.
+	MessageBufferSize int `json:"message_buffer_size,omitempty"`
+
+	//OverflowPolicy what to do with a message when the hub response channel is full.  Defaults to OverflowBlock.
+	OverflowPolicy OverflowPolicy `json:"overflow_policy,omitempty"`
+
+	//ErrorBufferSize capacity of the error channel.  Once full, further errors are dropped and counted.  Defaults to 5.
+	ErrorBufferSize int `json:"error_buffer_size,omitempty"`
+
+	//HeartbeatBufferSize capacity of the heartbeat channel.  Once full, further heartbeats are dropped and counted.  Defaults to 5.
+	HeartbeatBufferSize int `json:"heartbeat_buffer_size,omitempty"`
+
+	//CallbackBufferSize number of callbacks that may wait while an earlier one runs.  Once full, further callbacks are dropped and
+	//counted.  Defaults to 1000.
+	CallbackBufferSize int `json:"callback_buffer_size,omitempty"`
+
+	//QueryString additional query parameters appended to every request, alongside any already present in ConnectionURL.
+	QueryString map[string]string `json:"query_string,omitempty"`
+
+	//EnforceTLS force https/wss regardless of the ConnectionURL scheme.  When false, http maps to ws and https maps to wss.
+	EnforceTLS bool `json:"enforce_tls,omitempty"`
+
+	//AccessTokenProvider optional source of bearer tokens, invoked before negotiate, connect and reconnect.  A 401 from the peer
+	//triggers one refresh-and-retry before failing.
+	AccessTokenProvider AccessTokenProvider `json:"-"`
+
+	//AccessTokenPlacement header or query parameter for the provided token.  Defaults to AccessTokenHeader.
+	AccessTokenPlacement AccessTokenPlacement `json:"access_token_placement,omitempty"`
+
+	//ConnectedHookRetries extra attempts for an OnConnected hook that returns an error.  Zero means no retry.
+	ConnectedHookRetries int `json:"connected_hook_retries,omitempty"`
+
+	//ConnectedHookRetryDelay wait between OnConnected hook attempts.  Defaults to 1 second.
+	ConnectedHookRetryDelay time.Duration `json:"connected_hook_retry_delay,omitempty"`
+
+	//CursorStore optional persistence for the message cursor and connection token, used by Resume.
+	CursorStore CursorStore `json:"-"`
+
+	//KeepAliveTimeout silence from the peer after which the socket is dropped and reconnected, used when the negotiated
+	//value is missing or under a second.  ConnectionSlow is reported at 2/3 of it.  Defaults to 20 seconds.
+	KeepAliveTimeout time.Duration `json:"keep_alive_timeout,omitempty"`
+
+	//DisconnectTimeout how long to keep trying to reconnect before negotiating a new connection, used when the negotiated
+	//value is missing or under a second.  Defaults to 30 seconds.
+	DisconnectTimeout time.Duration `json:"disconnect_timeout,omitempty"`
+
+	//PingInterval send a websocket ping this often, keeping proxies and the peer from idling the socket out.  Each pong is
+	//a latency sample for Stats.  Zero disables.
+	PingInterval time.Duration `json:"ping_interval,omitempty"`
+
+	//Metrics optional sink for connection and invocation telemetry.  See the Metric constants for what's recorded.
+	Metrics Metrics `json:"-"`
+
+	//Tracer optional source of spans for negotiation, each dial attempt and each CallHub, with trace context propagated in
+	//negotiate and connect request headers.  See tracing.go.
+	Tracer Tracer `json:"-"`
+
+	//FrameRecorder optional hook handed every text frame sent and received, e.g. an NDJSONRecorder whose output Replay reads.
+	//Frames are recorded unredacted.
+	FrameRecorder FrameRecorder `json:"-"`
+
+	//Logger optional destination for debug traces of negotiation, dials, retries, frames and state changes.  A *slog.Logger
+	//works as is.  Nothing is logged, or even formatted, when unset.
+	Logger Logger `json:"-"`
+
+	//LogFramePayloads include frame contents in frame traces.  Off by default, logging only frame sizes.
+	LogFramePayloads bool `json:"log_frame_payloads,omitempty"`
+
+	//FrameRedactor optional scrubber applied to frame contents before they're logged, when LogFramePayloads is set.
+	FrameRedactor func(frame []byte) []byte `json:"-"`
+
+	//DedupWindow number of recent message cursors remembered.  Frames the peer replays with a remembered cursor are discarded
+	//and counted in DroppedEvents.  Zero disables de-duplication.
+	DedupWindow int `json:"dedup_window,omitempty"`
+
+	//CursorSaveInterval minimum time between cursor saves while messages are flowing.  Defaults to 1 second.
+	CursorSaveInterval time.Duration `json:"cursor_save_interval,omitempty"`
 }
 
 type serverMessage struct {
-	Cursor     string            `json:"C"`
-	Data       []json.RawMessage `json:"M"`
-	Result     json.RawMessage   `json:"R"`
-	Identifier string            `json:"I"`
-	Error      string            `json:"E"`
+	Cursor      string            `json:"C"`
+	Data        []json.RawMessage `json:"M"`
+	Result      json.RawMessage   `json:"R"`
+	Identifier  string            `json:"I"`
+	Error       string            `json:"E"`
+	GroupsToken string            `json:"G"`
 }
 
 //MessageDataPayload contains information from signalR peer based on subscription
@@ -76,12 +180,13 @@ type client struct {
 	//persist sanitized config
 	config Config
 
-	//store current state of connection
-	state ConnectionState
+	//store current state of connection, and when it was entered
+	state      ConnectionState
+	stateSince time.Time
 	//mutex to make changes to state threadsafe
 	stateMutex sync.RWMutex
-	//chan to broadcast the state of the signalr connection.
-	stateChan chan ConnectionState
+	//broadcasts the state of the signalr connection.
+	stateFeed *feed
 
 	//active websocket, assigned durinng connection process.
 	socket *websocket.Conn
@@ -95,71 +200,128 @@ type client struct {
 	nextID         int
 	callHubIDMutex sync.Mutex
 
-	//pipes read by lib consumers:
+	//pipes read by lib consumers.  each subscriber gets its own channel.
+
+	//errors from the lib and the peer.
+	errFeed *feed
+
+	//message stream from peer
+	messageFeed *feed
+
+	//heartbeat updates.
+	heartbeatFeed *feed
+
+	//raw frames from the peer, and the end of each Connect, for callbacks.
+	receivedFeed *feed
+	closedFeed   *feed
+
+	//runs registered callbacks off the read loop.
+	callbacks *dispatcher
 
-	//channel used to read errors.
-	errChan      chan error
-	errChanMutex sync.Mutex
+	//setup functions rerun on every new connection.
+	connectedHooks connectedHooks
 
-	//channel used to read message stream from peer
-	messageChan      chan MessageDataPayload
-	messageChanMutex sync.Mutex
+	//payload and wire byte counts for the websocket.
+	bytes *byteCounters
 
-	//channel used to listen to heartbeat updates.
-	heartbeatChan      chan Heartbeat
-	heartbeatChanMutex sync.Mutex
+	//frame counts, reconnects and round trip times, for Stats.
+	traffic *trafficCounters
+	latency *latencySamples
+
+	//counts of messages discarded by the overflow policy.
+	dropped *dropCounters
+
+	//lifetime of the running Connect, cancelled by Reset.
+	session      *session
+	sessionMutex sync.Mutex
 
 	messageID      string //hold reference to most recent messageId
+	groupsToken    string //most recent groups token, sent back on reconnect
+	negotiated     *negotiationResponse
 	messageIDMutex sync.Mutex
 
+	//recently seen cursors, for de-duplicating replayed frames.
+	cursors *cursorWindow
+
+	//last successful CursorStore save, for throttling.
+	cursorSavedAt   time.Time
+	cursorSaveMutex sync.Mutex
+
 	//external pipe for server messages
 	/*routedMessageChan      chan interface{}
 	routedMessageChanMutex sync.RWMutex */
 }
 
-func (c *client) setState(newState ConnectionState) {
-	c.stateMutex.Lock()
-	defer c.stateMutex.Unlock()
-
-	//cannot change state once broken.
-	if c.state < Broken {
-		c.state = newState
-		c.stateChan <- newState
+// httpScheme scheme used for plain http requests (negotiate) to the signalr peer.
+func (c *client) httpScheme() string {
+	switch c.config.ConnectionURL.Scheme {
+	case plainSocket:
+		return plainScheme
+	case socketScheme:
+		return defaultScheme
 	}
+
+	return c.config.ConnectionURL.Scheme
 }
 
-func (c *client) State() ConnectionState {
-	c.stateMutex.RLock()
-	defer c.stateMutex.RUnlock()
+// websocketScheme scheme used when dialing the signalr websocket.  custom schemes are passed through untouched.
+func (c *client) websocketScheme() string {
+	switch c.config.ConnectionURL.Scheme {
+	case plainScheme:
+		return plainSocket
+	case defaultScheme:
+		return socketScheme
+	}
 
-	return c.state
+	return c.config.ConnectionURL.Scheme
 }
 
-func (c *client) SubscribeToState() <-chan ConnectionState {
-	return c.stateChan
+// listenToWebSocketData receives all signals from the current websocket.
+// uses timeout based on signalr negotiation response, watched over by a keepalive watchdog.
+// returns the read error that ended the loop, with the socket closed.
+func (c *client) listenToWebSocketData(timeout time.Duration) error {
+	socket := c.currentSocket()
+	if socket == nil {
+		return NewSocketError("Unable to read from socket hub", errors.New("websocket not connected"))
+	}
+
+	defer c.closeSocket(socket)
+
+	w := c.startWatchdog(socket, timeout)
+	defer w.stop()
+
+	for {
+		_, data, socketReadErr := socket.ReadMessage()
+		if socketReadErr == nil {
+			w.alive()
+			c.recordFrame(FrameIn, data)
+			socketReadErr = c.receiveFrame(data)
+		}
+
+		if socketReadErr != nil && c.handleSocketReadErr(socketReadErr) {
+			return socketReadErr
+		}
+	}
 }
 
-// listenToWebSocketData receives all signals from the current websocket.
-// uses timeout based on signalr negotiation response
-// @TODO if the socket loop returns, make sure the state is properly communicated to consuming applications.
-func (c *client) listenToWebSocketData(timeout time.Duration) {
+// receiveFrame count, trace and publish a frame read from the socket, then dispatch it.  returns the error if the frame
+// isn't a serverMessage.
+func (c *client) receiveFrame(data []byte) error {
 	var (
-		message     serverMessage
+		message serverMessage
 	)
 
-	for {
-		c.socket.SetReadDeadline(time.Now().Add(timeout))
-		socketReadErr := c.socket.ReadJSON(&message);
-		if  socketReadErr != nil {
-			if c.handleSocketReadErr(socketReadErr) {
-				return
-			}
-			continue
-		}
+	c.recordMessageIn(len(data))
+	c.logFrameIn(data)
+	c.receivedFeed.publish(json.RawMessage(data))
 
-		//no error
-		c.dispatchMessage(message)
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
 	}
+
+	c.dispatchMessage(message)
+
+	return nil
 }
 
 // handleSocketReadErr logic for handling the kind of error found when trying to read from gorilla websocket.
@@ -168,7 +330,7 @@ func (c *client) handleSocketReadErr(err error) bool {
 	switch v := err.(type) {
 	case *json.UnmarshalTypeError:
 		c.sendErr(
-			newSocketError(
+			NewSocketError(
 				fmt.Sprintf(
 					"json.UnmarshalTypeError \n Value: %s\n Type: %s \n Offset: %d \n Struct: %s, Field %s",
 					v.Value,
@@ -183,7 +345,7 @@ func (c *client) handleSocketReadErr(err error) bool {
 		return false
 	case *json.UnsupportedTypeError:
 		c.sendErr(
-			newSocketError(
+			NewSocketError(
 				fmt.Sprintf(
 					"json.UnsupportedTypeError\n Type: %s \n ",
 					v.Type.String(),
@@ -194,7 +356,7 @@ func (c *client) handleSocketReadErr(err error) bool {
 		return false
 	case *json.UnsupportedValueError:
 		c.sendErr(
-			newSocketError(
+			NewSocketError(
 				fmt.Sprintf(
 					"json.UnsupportedValueError\n Value: %+v \n",
 					v.Value,
@@ -209,14 +371,14 @@ func (c *client) handleSocketReadErr(err error) bool {
 		)
 	default:
 		c.sendErr(
-			newSocketError(
+			NewSocketError(
 				"Unknown error type!!!!! Unable to convert inbound socketdata to serverMessage type.",
 				err,
 			),
 		)
 	}
 
-	c.setState(Disconnected)
+	c.changeState(Disconnected, err, 0)
 	c.sendErr(
 		fmt.Errorf("SignalR socket disconnected."),
 	)
@@ -226,68 +388,131 @@ func (c *client) handleSocketReadErr(err error) bool {
 
 func (c *client) dispatchMessage(msg serverMessage) {
 
-	if msg.Error != "" {
-		c.sendErr(HubMessageError(fmt.Sprintf("Error from signalr hub: %s", msg.Error)))
-		return
+	if msg.GroupsToken != "" {
+		c.updateGroupsToken(msg.GroupsToken)
 	}
 
+	//results of CallHub invocations (errors included) carry its identifier, and go straight back to the caller.
 	if len(msg.Identifier) > 0 {
-		c.updateMessageID(msg.Identifier)
 		if rc := c.responseChan(msg.Identifier); rc != nil {
 			rc <- &msg
 			c.delResponseChan(msg.Identifier)
+			return
+		}
+	}
+
+	if msg.Error != "" {
+		c.sendErr(HubMessageError(fmt.Sprintf("Error from signalr hub: %s", msg.Error)))
+		return
+	}
+
+	if msg.Cursor != "" && c.cursors.seenBefore(msg.Cursor) {
+		atomic.AddUint64(&c.dropped.duplicates, 1)
+		c.countDrop("duplicates")
+		return
+	}
+
+	if len(msg.Data) > 0 { //if "Data" is not empty, it's a hub push (subscription) message.
+		atomic.StoreUint32(&c.dropped.disconnected, 0)
+		for dataIndex := range msg.Data {
+			if atomic.LoadUint32(&c.dropped.disconnected) == 1 {
+				break
+			}
 
-		} else if len(msg.Data) > 0 { //if "Data" is not empty, presume it's a subscription response.
-			for dataIndex := range msg.Data {
-				var dataPayload MessageDataPayload
-				if parseErr := json.Unmarshal(msg.Data[dataIndex], &dataPayload); parseErr != nil {
-					c.sendErr(
-						newMDPParseError(
-							fmt.Sprintf(
-								"Unable to parse msg data into dataPayload type\n Data: %s \n ",
-								string(msg.Data[dataIndex]),
-							),
-							parseErr,
+			var dataPayload MessageDataPayload
+			if parseErr := json.Unmarshal(msg.Data[dataIndex], &dataPayload); parseErr != nil {
+				c.sendErr(
+					newMDPParseError(
+						fmt.Sprintf(
+							"Unable to parse msg data into dataPayload type\n Data: %s \n ",
+							string(msg.Data[dataIndex]),
+						),
+						parseErr,
+					),
+				)
+			} else if decodeErr := c.decodeArguments(&dataPayload); decodeErr != nil {
+				c.sendErr(
+					newArgumentDecodeError(
+						fmt.Sprintf(
+							"Unable to decode arguments\n Hub: %s \n Method: %s \n ",
+							dataPayload.HubName,
+							dataPayload.Method,
 						),
-					)
-				} else {
-					//fmt.Printf("sending data payload %+v\n", dataPayload)
-
-					c.messageChan <- dataPayload
-					//fmt.Println("sent payload")
-					c.sendHeartbeat(
-						NormalHeartbeat("Heartbeat refreshed by subscription signal."),
-					)
-				}
+						decodeErr,
+					),
+				)
+			} else {
+				c.messageFeed.publish(dataPayload)
+				c.sendHeartbeat(
+					c.newHeartbeat(HeartbeatMessage, "", "Heartbeat refreshed by subscription signal."),
+				)
 			}
-		} else {
-			c.sendHeartbeat(
-				AwkwardHeartbeat(fmt.Sprintf("No listener found for message with ID %s: %+v", msg.Identifier, msg)),
-			)
 		}
+	} else if len(msg.Identifier) > 0 {
+		c.sendHeartbeat(
+			c.newHeartbeat(
+				HeartbeatUnmatchedResponse,
+				msg.Identifier,
+				fmt.Sprintf("No listener found for message with ID %s: %+v", msg.Identifier, msg),
+			),
+		)
 	} else {
 		c.sendHeartbeat(
-			NormalHeartbeat("Default Heartbeat."),
+			c.newHeartbeat(HeartbeatKeepAlive, "", "Default Heartbeat."),
 		)
 	}
-}
 
-func (c *client) sendHeartbeat(hb Heartbeat) {
-	// if nothing is listening for a hearbeat yet, this is a noop.
-	if c.heartbeatChan == nil {
+	//a frame cut short by OverflowDisconnect is resent on reconnect, so neither the cursor nor the window may move past it.
+	if msg.Cursor != "" && atomic.LoadUint32(&c.dropped.disconnected) == 1 {
+		c.cursors.forget(msg.Cursor)
 		return
 	}
 
-	c.heartbeatChanMutex.Lock()
-	defer c.heartbeatChanMutex.Unlock()
-	c.heartbeatChan <- hb
+	//only move the cursor once the frame has been handed over, so a resume never skips it.
+	if msg.Cursor != "" {
+		c.updateMessageID(msg.Cursor)
+	}
+}
+
+// sendHeartbeat never blocks: heartbeats are dropped and counted for any subscriber whose channel is full.
+func (c *client) sendHeartbeat(hb HeartbeatEvent) {
+	c.heartbeatFeed.publish(hb)
+}
+
+// newHeartbeat stamp a heartbeat with the arrival time of the frame being dispatched.
+func (c *client) newHeartbeat(kind HeartbeatKind, invocationID string, detail string) HeartbeatEvent {
+	event := HeartbeatEvent{
+		Kind:         kind,
+		ReceivedAt:   time.Now(),
+		InvocationID: invocationID,
+		Detail:       detail,
+	}
+
+	if last := atomic.LoadInt64(&c.traffic.lastMessageAt); last != 0 {
+		event.ReceivedAt = time.Unix(0, last)
+
+		if previous := atomic.LoadInt64(&c.traffic.previousMessageAt); previous != 0 {
+			event.SincePrevious = event.ReceivedAt.Sub(time.Unix(0, previous))
+		}
+	}
+
+	return event
 }
 
 func (c *client) updateMessageID(msgID string) {
+	c.messageIDMutex.Lock()
+	c.messageID = msgID
+	c.messageIDMutex.Unlock()
+
+	c.saveCursor(c.context(), false)
+}
+
+// currentMessageID the most recent messageId, for reconnecting.
+func (c *client) currentMessageID() string {
 	c.messageIDMutex.Lock()
 	defer c.messageIDMutex.Unlock()
 
-	c.messageID = msgID
+	return c.messageID
 }
 
 func (c *client) responseChan(key string) chan *serverMessage {
@@ -297,11 +522,15 @@ func (c *client) responseChan(key string) chan *serverMessage {
 	return c.responseChannels[key]
 }
 
-func (c *client) setResponseChan(key string) {
+func (c *client) setResponseChan(key string) chan *serverMessage {
 	c.responseChannelMutex.Lock()
 	defer c.responseChannelMutex.Unlock()
 
-	c.responseChannels[key] = make(chan *serverMessage)
+	rc := make(chan *serverMessage)
+	c.responseChannels[key] = rc
+	c.gaugePendingInvocations()
+
+	return rc
 }
 
 func (c *client) delResponseChan(key string) {
@@ -311,33 +540,44 @@ func (c *client) delResponseChan(key string) {
 	if rc, ok := c.responseChannels[key]; ok {
 		close(rc)
 		delete(c.responseChannels, key)
+		c.gaugePendingInvocations()
 	}
 }
 
-func (c *client) sendErr(err error) {
-	c.errChanMutex.Lock()
-	defer c.errChanMutex.Unlock()
+// clearResponseChans close every pending response channel, failing the CallHub invocations waiting on them.
+func (c *client) clearResponseChans() {
+	c.responseChannelMutex.Lock()
+	defer c.responseChannelMutex.Unlock()
+
+	for key, rc := range c.responseChannels {
+		close(rc)
+		delete(c.responseChannels, key)
+	}
 
-	c.errChan <- err
+	c.gaugePendingInvocations()
 }
 
-// ListenToErrors get reference to errors generated by signalr peer
+// sendErr never blocks: errors are dropped and counted for any subscriber whose channel is full.
+func (c *client) sendErr(err error) {
+	c.errFeed.publish(err)
+}
+
+// ListenToErrors get a new channel of errors generated by the lib or signalr peer.  see ErrorSubscription to be able to unsubscribe.
 func (c *client) ListenToErrors() <-chan error {
-	return c.errChan
+	ch, _ := c.ErrorSubscription()
+	return ch
 }
 
-// ListenToHubResponses get reference to messages generated by signalr peer
+// ListenToHubResponses get a new channel of messages generated by signalr peer.  see HubResponseSubscription to be able to unsubscribe.
 func (c *client) ListenToHubResponses() <-chan MessageDataPayload {
-	return c.messageChan
+	ch, _ := c.HubResponseSubscription()
+	return ch
 }
 
-// ListenToHeartbeat get reference to heartbeat messages generated by signalr peer
+// ListenToHeartbeat get a new channel of heartbeat messages generated by signalr peer.  see HeartbeatSubscription to be able to unsubscribe.
 func (c *client) ListenToHeartbeat() <-chan Heartbeat {
-	if c.heartbeatChan == nil {
-		c.heartbeatChan = make(chan Heartbeat)
-	}
-
-	return c.heartbeatChan
+	ch, _ := c.HeartbeatSubscription()
+	return ch
 }
 
 //New generates a new client based on user data.  Specifying an invalid url will not fail until the connection steps.
@@ -347,8 +587,13 @@ func New(c Config) Connection {
 		c.ConnectionURL = &url.URL{}
 	}
 
-	// Don't care what the prior scheme was.  Force HTTPS.
-	c.ConnectionURL.Scheme = "https"
+	// copy the url so sanitizing it doesn't mutate the consumer's value.
+	connectionURL := *c.ConnectionURL
+	c.ConnectionURL = &connectionURL
+
+	if c.ConnectionURL.Scheme == "" || c.EnforceTLS {
+		c.ConnectionURL.Scheme = defaultScheme
+	}
 
 	if c.ConnectionURL.Host == "" {
 		c.ConnectionURL.Host = "localhost:1337"
@@ -367,18 +612,50 @@ func New(c Config) Connection {
 	}
 
 	if c.Client == nil {
-		c.Client = &http.Client{}
+		c.Client = newHTTPClient(c)
+	}
+
+	if c.ErrorBufferSize <= 0 {
+		c.ErrorBufferSize = defaultEventBufferSize
+	}
+
+	if c.HeartbeatBufferSize <= 0 {
+		c.HeartbeatBufferSize = defaultEventBufferSize
+	}
+
+	if c.CallbackBufferSize <= 0 {
+		c.CallbackBufferSize = defaultCallbackBufferSize
+	}
+
+	if c.CursorSaveInterval <= 0 {
+		c.CursorSaveInterval = defaultCursorSaveInterval
 	}
 
 	new := &client{
 		config:           c,
 		state:            Ready,
-		stateChan:        make(chan ConnectionState, 5),
+		stateSince:       time.Now(),
+		stateFeed:        newFeed(),
 		nextID:           1,
-		errChan:          make(chan error, 5),
-		messageChan:      make(chan MessageDataPayload),
+		bytes:            &byteCounters{},
+		dropped:          &dropCounters{},
+		traffic:          &trafficCounters{},
+		latency:          &latencySamples{},
+		cursors:          newCursorWindow(c.DedupWindow),
+		errFeed:          newFeed(),
+		messageFeed:      newFeed(),
+		heartbeatFeed:    newFeed(),
+		receivedFeed:     newFeed(),
+		closedFeed:       newFeed(),
 		responseChannels: map[string]chan *serverMessage{},
 	}
 
+	new.callbacks = &dispatcher{
+		limit:   c.CallbackBufferSize,
+		onPanic: new.reportCallbackPanic,
+		onDrop:  new.reportDroppedCallback,
+	}
+	new.stateFeed.subscribe(new.watchConnected, func() {})
+
 	return new
 }
@@ -30,16 +30,16 @@ func TestNewDefault(t *testing.T) {
 		t.Errorf("default state expected to be %+v, received %+v", Ready, cast.state)
 	}
 
-	if cast.errChan == nil {
-		t.Errorf("default err chan expected, <nil> found")
+	if cast.errFeed == nil {
+		t.Errorf("default err feed expected, <nil> found")
 	}
 
 	if cast.responseChannels == nil {
 		t.Errorf("default response channels map expected.  <nil> found")
 	}
 
-	if defaultChan, ok := cast.responseChannels["default"]; !ok || defaultChan == nil {
-		t.Errorf("Invalid default response channel detected. default key val exists: %t, channel val: %+v", ok, defaultChan)
+	if pending := cast.Stats().PendingInvocations; pending != 0 {
+		t.Errorf("expected no pending invocations before any call, found %d", pending)
 	}
 
 	sanitizedCfg := cast.config
@@ -104,16 +104,16 @@ func TestNewCustom(t *testing.T) {
 		t.Errorf("default state expected to be %+v, received %+v", Ready, cast.state)
 	}
 
-	if cast.errChan == nil {
-		t.Errorf("default err chan expected, <nil> found")
+	if cast.errFeed == nil {
+		t.Errorf("default err feed expected, <nil> found")
 	}
 
 	if cast.responseChannels == nil {
 		t.Errorf("default response channels map expected.  <nil> found")
 	}
 
-	if defaultChan, ok := cast.responseChannels["default"]; !ok || defaultChan == nil {
-		t.Errorf("Invalid default response channel detected. default key val exists: %t, channel val: %+v", ok, defaultChan)
+	if pending := cast.Stats().PendingInvocations; pending != 0 {
+		t.Errorf("expected no pending invocations before any call, found %d", pending)
 	}
 
 	sanitizedCfg := cast.config
@@ -140,7 +140,7 @@ func TestNewCustom(t *testing.T) {
 
 }
 
-func TestsetState(t *testing.T) {
+func TestSetState(t *testing.T) {
 	//Assemble
 
 	cfg := Config{}
@@ -159,16 +159,153 @@ func TestsetState(t *testing.T) {
 }
 
 func TestState(t *testing.T) {
+	//Assemble
 	cfg := Config{}
 	conn := New(cfg)
 	testClient := conn.(*client)
 
 	//Act
-	testClient.setState(Broken)
+	illegal := testClient.setState(Broken)
+	for _, state := range []ConnectionState{Connecting, Disconnected, Broken} {
+		if err := testClient.setState(state); err != nil {
+			t.Fatalf("unexpected error moving to %s: %v", state, err)
+		}
+	}
 
 	//Assert
+	if cast, ok := illegal.(InvalidTransitionError); !ok || cast.From != Ready || cast.To != Broken {
+		t.Errorf("expected an InvalidTransitionError from Ready to Broken, found %T: %v", illegal, illegal)
+	}
+
+	if testClient.State() != Broken {
+		t.Errorf("expected Broken, found %s", testClient.State())
+	}
 
 	if testClient.state != testClient.State() {
 		t.Errorf("getState not retrieving proper value.  expected %+v, got %+v", testClient.state, testClient.State())
 	}
 }
+
+func TestNewSchemes(t *testing.T) {
+	cases := []struct {
+		configured string
+		enforceTLS bool
+		http       string
+		websocket  string
+	}{
+		{"", false, "https", "wss"},
+		{"https", false, "https", "wss"},
+		{"http", false, "http", "ws"},
+		{"ws", false, "http", "ws"},
+		{"wss", false, "https", "wss"},
+		{"http", true, "https", "wss"},
+		{"ws", true, "https", "wss"},
+		{"custom", false, "custom", "custom"},
+	}
+
+	for _, tc := range cases {
+		connURL := &url.URL{Scheme: tc.configured, Host: "localhost:1337"}
+
+		c := New(Config{ConnectionURL: connURL, EnforceTLS: tc.enforceTLS}).(*client)
+
+		if got := c.httpScheme(); got != tc.http {
+			t.Errorf("scheme %q (enforce %t): expected http scheme %q, found %q", tc.configured, tc.enforceTLS, tc.http, got)
+		}
+
+		if got := c.websocketScheme(); got != tc.websocket {
+			t.Errorf("scheme %q (enforce %t): expected websocket scheme %q, found %q", tc.configured, tc.enforceTLS, tc.websocket, got)
+		}
+
+		if connURL.Scheme != tc.configured {
+			t.Errorf("constructor mutated consumer url scheme from %q to %q", tc.configured, connURL.Scheme)
+		}
+	}
+}
+
+// finishesWithin fails the test if fn blocks for longer than timeout.
+func finishesWithin(t *testing.T, timeout time.Duration, what string, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%s blocked with nobody listening", what)
+	}
+}
+
+func TestSendErrNeverBlocks(t *testing.T) {
+	c := New(Config{ErrorBufferSize: 3}).(*client)
+	errs, _ := c.ErrorSubscription()
+
+	finishesWithin(t, time.Second, "sendErr", func() {
+		for i := 0; i < 100; i++ {
+			c.sendErr(TimeoutError("nobody is listening"))
+		}
+	})
+
+	if len(errs) != 3 {
+		t.Errorf("expected buffer of 3 errors, found %d", len(errs))
+	}
+
+	if dropped := c.DroppedEvents().Errors; dropped != 97 {
+		t.Errorf("expected 97 dropped errors, found %d", dropped)
+	}
+}
+
+func TestSendHeartbeatNeverBlocks(t *testing.T) {
+	c := New(Config{}).(*client)
+
+	//nothing listening yet: noop, not a drop.
+	c.sendHeartbeat(c.newHeartbeat(HeartbeatKeepAlive, "", "before listening"))
+
+	hb := c.ListenToHeartbeat()
+
+	finishesWithin(t, time.Second, "sendHeartbeat", func() {
+		for i := 0; i < 100; i++ {
+			c.sendHeartbeat(c.newHeartbeat(HeartbeatKeepAlive, "", "nobody is reading"))
+		}
+	})
+
+	if len(hb) != defaultEventBufferSize {
+		t.Errorf("expected buffer of %d heartbeats, found %d", defaultEventBufferSize, len(hb))
+	}
+
+	if dropped := c.DroppedEvents().Heartbeats; dropped != uint64(100-defaultEventBufferSize) {
+		t.Errorf("expected %d dropped heartbeats, found %d", 100-defaultEventBufferSize, dropped)
+	}
+}
+
+func TestSetStateNeverBlocks(t *testing.T) {
+	c := New(Config{}).(*client)
+	c.SubscribeToState()
+	c.setState(Connecting)
+
+	finishesWithin(t, time.Second, "setState", func() {
+		for i := 0; i < 50; i++ {
+			c.setState(Reconnecting)
+			c.setState(Connected)
+		}
+	})
+
+	if c.State() != Connected {
+		t.Errorf("state changes should apply even when undelivered, found %d", c.State())
+	}
+}
+
+func TestDispatchErrorFloodNeverBlocks(t *testing.T) {
+	c := New(Config{}).(*client)
+	c.ListenToErrors()
+	c.ListenToHeartbeat()
+
+	//the sixth hub error used to deadlock the read loop.
+	finishesWithin(t, time.Second, "dispatchMessage", func() {
+		for i := 0; i < 100; i++ {
+			c.dispatchMessage(serverMessage{Error: "boom"})
+			c.dispatchMessage(serverMessage{})
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package signalr
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// CompressionStats byte counts for the websocket, used to judge what permessage-deflate is buying you.
+// Payload bytes are the uncompressed frame contents; wire bytes are what crossed the underlying connection,
+// including TLS and websocket framing overhead.
+type CompressionStats struct {
+	//Negotiated true if the peer accepted permessage-deflate on the current socket.
+	Negotiated bool
+
+	PayloadBytesIn  uint64
+	PayloadBytesOut uint64
+	WireBytesIn     uint64
+	WireBytesOut    uint64
+}
+
+// byteCounters running totals, updated atomically.  kept behind a pointer so the uint64s stay 64-bit aligned.
+type byteCounters struct {
+	payloadIn  uint64
+	payloadOut uint64
+	wireIn     uint64
+	wireOut    uint64
+	negotiated uint32
+}
+
+// countingConn net.Conn wrapper tallying the bytes that actually cross the wire.
+type countingConn struct {
+	net.Conn
+	counters *byteCounters
+}
+
+// Read implement net.Conn
+func (cc *countingConn) Read(b []byte) (int, error) {
+	n, err := cc.Conn.Read(b)
+	atomic.AddUint64(&cc.counters.wireIn, uint64(n))
+	return n, err
+}
+
+// Write implement net.Conn
+func (cc *countingConn) Write(b []byte) (int, error) {
+	n, err := cc.Conn.Write(b)
+	atomic.AddUint64(&cc.counters.wireOut, uint64(n))
+	return n, err
+}
+
+// countingDialContext wrap a dial function so every connection it creates is counted.
+func countingDialContext(
+	dial func(ctx context.Context, network, addr string) (net.Conn, error),
+	counters *byteCounters,
+) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &countingConn{Conn: conn, counters: counters}, nil
+	}
+}
+
+// configureCompression apply the write compression settings to a freshly dialed socket and note whether the peer agreed.
+func (c *client) configureCompression(socket *websocket.Conn, extensions string) {
+	if !c.config.EnableCompression {
+		return
+	}
+
+	negotiated := strings.Contains(extensions, "permessage-deflate")
+	if negotiated {
+		atomic.StoreUint32(&c.bytes.negotiated, 1)
+	} else {
+		atomic.StoreUint32(&c.bytes.negotiated, 0)
+	}
+
+	socket.EnableWriteCompression(negotiated)
+
+	if negotiated && c.config.CompressionLevel != 0 {
+		if err := socket.SetCompressionLevel(c.config.CompressionLevel); err != nil {
+			c.sendErr(NewSocketError("Unable to set websocket compression level", err))
+		}
+	}
+}
+
+// CompressionStats snapshot of payload vs. wire byte counts since the client was created.
+func (c *client) CompressionStats() CompressionStats {
+	return CompressionStats{
+		Negotiated:      atomic.LoadUint32(&c.bytes.negotiated) == 1,
+		PayloadBytesIn:  atomic.LoadUint64(&c.bytes.payloadIn),
+		PayloadBytesOut: atomic.LoadUint64(&c.bytes.payloadOut),
+		WireBytesIn:     atomic.LoadUint64(&c.bytes.wireIn),
+		WireBytesOut:    atomic.LoadUint64(&c.bytes.wireOut),
+	}
+}
@@ -0,0 +1,74 @@
+package signalr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCompressionStats(t *testing.T) {
+	//Assemble
+	frame := `{"C":"d-1","M":[],"Padding":"` + strings.Repeat("market data ", 1000) + `"}`
+
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.EnableWriteCompression(true)
+		conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	c := New(Config{
+		ConnectionURL:     serverURL,
+		EnableCompression: true,
+		CompressionLevel:  9,
+	}).(*client)
+	c.setState(Connecting)
+
+	if err := c.dialWebSocket(c.config.ConnectPath, url.Values{}, time.Second, time.Time{}); err != nil {
+		t.Fatalf("unable to dial test server: %v", err)
+	}
+
+	//Act
+	c.listenToWebSocketData(time.Second)
+
+	//Assert
+	stats := c.CompressionStats()
+
+	if !stats.Negotiated {
+		t.Errorf("expected permessage-deflate to be negotiated")
+	}
+
+	if stats.PayloadBytesIn < uint64(len(frame)) {
+		t.Errorf("expected at least %d payload bytes in, found %d", len(frame), stats.PayloadBytesIn)
+	}
+
+	if stats.WireBytesIn == 0 || stats.WireBytesIn >= stats.PayloadBytesIn {
+		t.Errorf("expected compressed wire bytes below payload bytes, found wire %d payload %d", stats.WireBytesIn, stats.PayloadBytesIn)
+	}
+
+	if stats.WireBytesOut == 0 {
+		t.Errorf("expected handshake bytes to be counted on the way out")
+	}
+}
+
+func TestCompressionDisabledByDefault(t *testing.T) {
+	c := New(Config{}).(*client)
+
+	if c.newDialer(connectHandshakeTimeout).EnableCompression {
+		t.Errorf("compression should be opt-in")
+	}
+}
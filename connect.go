@@ -1,17 +1,23 @@
 package signalr
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// maxDialAttempts websocket dial attempts before giving up on a connect or reconnect.
+const maxDialAttempts = 5
+
 type negotiationResponse struct {
 	ConnectionToken         string
 	URL                     string
@@ -26,73 +32,154 @@ type negotiationResponse struct {
 }
 
 func (c *client) Connect(hubs []string) error {
-	if c.State() == Broken {
-		return ConnectError("Client in broken state.  Check config or create new client instance.")
+	return c.run(hubs, nil)
+}
+
+// Resume connect by reconnecting with the cursor saved in the configured CursorStore, so messages the peer buffered
+// while no process was listening are replayed.  With nothing stored it behaves like Connect, and if the peer no longer
+// knows the stored connection a ResumeError is reported, a fresh connection negotiated instead, and GapDetected reported
+// once it's up.
+func (c *client) Resume(hubs []string) error {
+	if c.config.CursorStore == nil {
+		return ConnectError("No CursorStore configured.  Set Config.CursorStore or call Connect.")
 	}
 
-	c.state = Connecting
+	cursor, err := c.config.CursorStore.Load(context.Background())
+	if err != nil {
+		err = newCursorStoreError("Unable to load cursor", err)
+		c.sendErr(err)
+		return err
+	}
 
-	nResp, negotiationErr := c.negotiate()
-	if negotiationErr != nil {
-		return negotiationErr
+	if cursor == nil || cursor.ConnectionToken == "" {
+		return c.run(hubs, nil)
 	}
 
-	if err := c.connectWebSocket(nResp, hubs); err != nil {
+	return c.run(hubs, cursor)
+}
+
+// run drive a connection until it breaks or is Reset, resuming from cursor when one is given.
+func (c *client) run(hubs []string, cursor *Cursor) (err error) {
+	if c.State() == Broken {
+		return ConnectError("Client in broken state.  Call Reset or create new client instance.")
+	}
+
+	if err = c.setState(Connecting); err != nil {
 		return err
 	}
 
-	return c.handleSocketCommunication(nResp, hubs)
+	s := c.startSession()
+
+	defer func() {
+		c.saveCursor(context.Background(), true)
+		c.endSession(s)
+		c.closedFeed.publish(closedEvent{err: err})
+	}()
+
+	var (
+		nResp     *negotiationResponse
+		resumeErr error
+	)
+
+	if cursor != nil {
+		nResp, resumeErr = c.resumeWebSocket(*cursor, hubs)
+	}
 
+	if nResp == nil {
+		if nResp, err = c.connectFresh(hubs); err != nil {
+			return err
+		}
+	}
+
+	c.saveCursor(c.context(), true)
+
+	if resumeErr != nil {
+		c.reportGap(cursor.MessageID, resumeErr)
+	}
+
+	return c.handleSocketCommunication(nResp, hubs)
 }
 
 func (c *client) handleSocketCommunication(nResp *negotiationResponse, hubs []string) error {
 	for {
-		c.listenToWebSocketData(time.Second * time.Duration(nResp.KeepAliveTimeout)) //20 seconds, as of 2019.04.16 --DM
+		readErr := c.listenToWebSocketData(c.keepAliveTimeout(nResp)) //20 seconds, as of 2019.04.16 --DM
+
+		//Reset closed the socket on purpose; don't fight it.
+		if ctxErr := c.context().Err(); ctxErr != nil {
+			return NewBrokenWebSocketError("handleSocketCommunication", ctxErr)
+		}
 
 		//if the code gets here, that means the socket disconnected.
-		c.setState(Reconnecting)
-		if err := c.reconnectWebSocket(nResp, hubs); err != nil {
+		c.saveCursor(c.context(), true)
+		c.changeState(Reconnecting, readErr, 0)
+		err := c.reconnectWebSocket(nResp, hubs, time.Now().Add(c.disconnectTimeout(nResp)))
+
+		if reconnectErr, exhausted := err.(SocketConnectionError); exhausted {
+			//the peer has most likely forgotten this connection.  start over, and own up to what was missed.
+			lastMessageID := c.currentMessageID()
+			if c.logging() {
+				c.config.Logger.Info("signalr reconnect failed, negotiating a new connection", "lastMessageId", lastMessageID, "err", reconnectErr)
+			}
+			if nResp, err = c.connectFresh(hubs); err == nil {
+				c.saveCursor(c.context(), true)
+				c.reportGap(lastMessageID, reconnectErr)
+			}
+		}
+
+		if err != nil {
 			return err
 		}
+
+		atomic.AddUint64(&c.traffic.reconnects, 1)
 	}
 }
 
+// connectFresh negotiate a new connection and dial it.  running out of dial attempts breaks the client.
+func (c *client) connectFresh(hubs []string) (*negotiationResponse, error) {
+	nResp, err := c.negotiate()
+	if err != nil {
+		return nil, err
+	}
+
+	c.setNegotiated(nResp)
+
+	if err = c.connectWebSocket(nResp, hubs); err != nil {
+		if _, exhausted := err.(SocketConnectionError); exhausted {
+			c.changeState(Broken, err, maxDialAttempts)
+		}
+		return nil, err
+	}
+
+	return nResp, nil
+}
+
 func (c *client) negotiate() (*negotiationResponse, error) {
 	var (
-		request  *http.Request
 		response *http.Response
 		result   negotiationResponse
 		err      error
 		body     []byte
 	)
 
-	negotiationURL := url.URL{
-		Scheme: c.config.ConnectionURL.Scheme,
-		Host:   c.config.ConnectionURL.Host,
-		Path:   c.config.NegotiatePath,
-		RawQuery: url.Values{
-			"clientProtocol": []string{"1.5"},
-			"_":              []string{fmt.Sprintf("%d", time.Now().Unix()*1000)},
-		}.Encode(),
-	}
-
-	if request, err = http.NewRequest("GET", negotiationURL.String(), nil); err != nil {
-		err = NewNegotiationError("Unable to create new request", err)
-		c.sendErr(err)
-		c.setState(Broken)
-		return nil, err
-	}
+	ctx, span := c.startSpan(SpanNegotiate)
+	defer func() { endSpan(span, err) }()
 
-	for k, values := range c.config.RequestHeaders {
-		for _, val := range values {
-			request.Header.Add(k, val)
+	response, err = c.negotiateRequest(ctx)
+	if err == nil && isUnauthorized(response) {
+		//refresh the access token and retry once before giving up.
+		response.Body.Close()
+		if c.logging() {
+			c.config.Logger.Debug("signalr negotiate unauthorized, refreshing access token")
+		}
+		if response, err = c.negotiateRequest(ctx); err == nil && isUnauthorized(response) {
+			response.Body.Close()
+			err = newAccessTokenError("Access token rejected during negotiation", fmt.Errorf("HTTP %s", response.Status))
 		}
 	}
 
-	if response, err = c.config.Client.Do(request); err != nil {
-		err = NewNegotiationError("Unable to execute negotiation request", err)
+	if err != nil {
 		c.sendErr(err)
-		c.setState(Broken)
+		c.changeState(Broken, err, 0)
 		return nil, err
 	}
 
@@ -101,7 +188,7 @@ func (c *client) negotiate() (*negotiationResponse, error) {
 	if body, err = ioutil.ReadAll(response.Body); err != nil {
 		err = NewNegotiationError("Unable to read negotiation response body", err)
 		c.sendErr(err)
-		c.setState(Broken)
+		c.changeState(Broken, err, 0)
 		return nil, err
 	}
 
@@ -111,76 +198,77 @@ func (c *client) negotiate() (*negotiationResponse, error) {
 			err,
 		)
 		c.sendErr(err)
-		c.setState(Broken)
+		c.changeState(Broken, err, 0)
 		return nil, err
 	}
 
 	return &result, nil
 }
 
-func (c *client) connectWebSocket(params *negotiationResponse, hubs []string) error {
-	if c.State() == Broken {
-		return NewBrokenWebSocketError(
-			"connectWebSocket",
-			fmt.Errorf("unable to connect, client object in broken state"),
-		)
+// negotiateRequest build and execute a single negotiation request, with a fresh access token if configured.  ctx carries
+// the negotiate span, propagated to the peer.
+func (c *client) negotiateRequest(ctx context.Context) (*http.Response, error) {
+	var (
+		request  *http.Request
+		response *http.Response
+		err      error
+	)
+
+	header := c.requestHeader()
+	query := url.Values{
+		"clientProtocol": []string{"1.5"},
+		"_":              []string{fmt.Sprintf("%d", time.Now().Unix()*1000)},
 	}
 
-	connectionURL := url.URL{
-		Scheme: "wss",
-		Host:   c.config.ConnectionURL.Host,
-		Path:   c.config.ConnectPath,
-		RawQuery: url.Values{
-			"transport":       []string{"webSockets"},
-			"clientProtocol":  []string{params.ProtocolVersion},
-			"connectionToken": []string{params.ConnectionToken},
-			"connectionData":  []string{string(castHubNamesToString(hubs))},
-			"_":               []string{fmt.Sprintf("%d", time.Now().Unix()*1000)},
-		}.Encode(),
+	if err = c.authorize(ctx, header, query); err != nil {
+		return nil, err
 	}
+	c.injectTrace(ctx, header)
+
+	negotiationURL := c.endpointURL(c.httpScheme(), c.config.NegotiatePath, query)
 
-	socketDialer := &websocket.Dialer{
-		Proxy:            http.ProxyFromEnvironment,
-		HandshakeTimeout: 45 * time.Second,
-		Jar:              c.config.Client.Jar,
+	if c.logging() {
+		c.config.Logger.Debug("signalr negotiate", "url", logURL(negotiationURL))
 	}
 
-	var (
-		err  error
-		resp *http.Response
-	)
+	if request, err = http.NewRequestWithContext(ctx, "GET", negotiationURL.String(), nil); err != nil {
+		return nil, NewNegotiationError("Unable to create new request", err)
+	}
 
-	for i := 0; i <= 5; i++ {
-		if i == 5 {
-			c.setState(Broken)
-			err = SocketConnectionError("MAX RETRIES REACHED.  ABORTING CONNECTION.")
-			c.sendErr(err)
-			return err
-		}
+	request.Header = header
 
-		backoff := math.Pow(2.0, float64(i))
-		time.Sleep(time.Second * time.Duration(backoff))
-		//@todo incorporate the currently ignored http response parameter into socketConnectionError
-		if c.socket, resp, err = socketDialer.Dial(connectionURL.String(), c.config.RequestHeaders); err != nil {
-			c.sendErr(
-				SocketConnectionError(
-					fmt.Sprintf(
-						"\n Unable to dial successfully: %s \n HTTP Response: %+v\n",
-						err.Error(),
-						resp,
-					),
-				),
-			)
-		} else {
-			c.setState(Connected)
-			break
-		}
+	if response, err = c.config.Client.Do(request); err != nil {
+		return nil, NewNegotiationError("Unable to execute negotiation request", err)
 	}
 
-	return nil
+	if c.logging() {
+		c.config.Logger.Debug("signalr negotiate response", "status", response.StatusCode)
+	}
+
+	return response, nil
 }
 
-func (c *client) reconnectWebSocket(params *negotiationResponse, hubs []string) error {
+func (c *client) connectWebSocket(params *negotiationResponse, hubs []string) error {
+	if c.State() == Broken {
+		return NewBrokenWebSocketError(
+			"connectWebSocket",
+			fmt.Errorf("unable to connect, client object in broken state"),
+		)
+	}
+
+	query := url.Values{
+		"transport":       []string{"webSockets"},
+		"clientProtocol":  []string{params.ProtocolVersion},
+		"connectionToken": []string{params.ConnectionToken},
+		"connectionData":  []string{string(castHubNamesToString(hubs))},
+	}
+
+	return c.dialWebSocket(c.config.ConnectPath, query, connectHandshakeTimeout, time.Time{})
+}
+
+// reconnectWebSocket pick the connection back up where the cursor left off.  Attempts stop at giveUpAt, since the peer
+// will have forgotten the connection by then.
+func (c *client) reconnectWebSocket(params *negotiationResponse, hubs []string, giveUpAt time.Time) error {
 	if c.State() == Broken {
 		return NewBrokenWebSocketError(
 			"reconnectWebSocket",
@@ -189,66 +277,208 @@ func (c *client) reconnectWebSocket(params *negotiationResponse, hubs []string)
 	}
 
 	// if we get here without having recieved a single message, try to connect instead.
-	if c.messageID == "" {
+	if c.currentMessageID() == "" {
 		return c.connectWebSocket(params, hubs)
 
 	}
 
-	connectionURL := url.URL{
-		Scheme: "wss",
-		Host:   c.config.ConnectionURL.Host,
-		Path:   c.config.ReconnectPath,
-		RawQuery: url.Values{
-			"transport":       []string{"webSockets"},
-			"clientProtocol":  []string{params.ProtocolVersion},
-			"connectionToken": []string{params.ConnectionToken},
-			"connectionData":  []string{string(castHubNamesToString(hubs))},
-			"messageId":       []string{c.messageID},
-			"_":               []string{fmt.Sprintf("%d", time.Now().Unix()*1000)},
-		}.Encode(),
+	return c.dialWebSocket(c.config.ReconnectPath, c.reconnectQuery(params, hubs), reconnectHandshakeTimeout, giveUpAt)
+}
+
+// resumeWebSocket make a single reconnect attempt with a stored cursor.  Returns the dial error if the peer won't take it
+// back, leaving the client ready to negotiate from scratch.
+func (c *client) resumeWebSocket(cursor Cursor, hubs []string) (*negotiationResponse, error) {
+	nResp := c.restoreCursor(cursor)
+	socketDialer := c.newDialer(reconnectHandshakeTimeout)
+	query := c.reconnectQuery(nResp, hubs)
+
+	resp, err := c.dialOnce(socketDialer, c.config.ReconnectPath, query, 1)
+	if isUnauthorized(resp) {
+		resp, err = c.dialOnce(socketDialer, c.config.ReconnectPath, query, 1)
+	}
+
+	if err != nil {
+		if c.logging() {
+			c.config.Logger.Info("signalr stored cursor rejected, negotiating a new connection", "err", err)
+		}
+		c.sendErr(newResumeError("Stored cursor rejected, negotiating a new connection", err))
+		c.setNegotiated(nil)
+		return nil, err
+	}
+
+	c.changeState(Connected, nil, 1)
+
+	return nResp, nil
+}
+
+// reconnectQuery query parameters for picking an existing connection back up where the cursor left off.
+func (c *client) reconnectQuery(params *negotiationResponse, hubs []string) url.Values {
+	cursor, _ := c.cursor()
+
+	query := url.Values{
+		"transport":       []string{"webSockets"},
+		"clientProtocol":  []string{params.ProtocolVersion},
+		"connectionToken": []string{params.ConnectionToken},
+		"connectionData":  []string{string(castHubNamesToString(hubs))},
+		"messageId":       []string{cursor.MessageID},
 	}
 
-	socketDialer := &websocket.Dialer{
-		Proxy:            http.ProxyFromEnvironment,
-		HandshakeTimeout: 30 * time.Second,
-		Jar:              c.config.Client.Jar,
+	if cursor.GroupsToken != "" {
+		query.Set("groupsToken", cursor.GroupsToken)
 	}
 
+	return query
+}
+
+// dialWebSocket dial the signalr peer at the given endpoint, backing off exponentially between attempts.
+// A 401 from the peer refreshes the access token and retries once before the client is considered broken.  Running out
+// of attempts, or reaching giveUpAt when it's set, returns a SocketConnectionError and leaves the state alone; the caller
+// decides whether that's fatal.
+func (c *client) dialWebSocket(endpoint string, query url.Values, handshakeTimeout time.Duration, giveUpAt time.Time) error {
+	socketDialer := c.newDialer(handshakeTimeout)
+
 	var (
-		err  error
-		resp *http.Response
+		err         error
+		resp        *http.Response
+		authRetried bool
 	)
 
-	for i := 0; i <= 5; i++ {
-		if i == 5 {
-			c.setState(Broken)
+	for i := 0; i <= maxDialAttempts; i++ {
+		if i == maxDialAttempts {
 			err = SocketConnectionError("MAX RETRIES REACHED.  ABORTING CONNECTION.")
+			if c.logging() {
+				c.config.Logger.Warn("signalr dial giving up", "endpoint", endpoint, "attempts", i)
+			}
 			c.sendErr(err)
 			return err
 		}
 
-		backoff := math.Pow(2.0, float64(i))
-		time.Sleep(time.Second * time.Duration(backoff))
-		//@todo incorporate the currently ignored http response parameter into socketConnectionError
-		if c.socket, resp, err = socketDialer.Dial(connectionURL.String(), c.config.RequestHeaders); err != nil {
-			c.sendErr(
-				SocketConnectionError(
-					fmt.Sprintf(
-						"\n Unable to dial successfully: %s \n HTTP Response: %+v\n",
-						err.Error(),
-						resp,
-					),
-				),
-			)
-		} else {
-			c.setState(Connected)
+		backoff := time.Second * time.Duration(math.Pow(2.0, float64(i)))
+		if !giveUpAt.IsZero() && time.Now().Add(backoff).After(giveUpAt) {
+			err = SocketConnectionError("DISCONNECT TIMEOUT REACHED.  ABORTING RECONNECT.")
+			if c.logging() {
+				c.config.Logger.Warn("signalr dial giving up, disconnect timeout reached", "endpoint", endpoint, "attempts", i)
+			}
+			c.sendErr(err)
+			return err
+		}
+
+		if c.logging() {
+			c.config.Logger.Debug("signalr dial backoff", "endpoint", endpoint, "attempt", i+1, "backoff", backoff)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-c.context().Done():
+			return NewBrokenWebSocketError("dialWebSocket", c.context().Err())
+		}
+
+		for {
+			if resp, err = c.dialOnce(socketDialer, endpoint, query, i+1); !isUnauthorized(resp) || authRetried {
+				break
+			}
+			//token likely expired between attempts.  dialOnce fetches a fresh one.
+			authRetried = true
+			if c.logging() {
+				c.config.Logger.Debug("signalr dial unauthorized, refreshing access token", "endpoint", endpoint)
+			}
+		}
+
+		if err == nil {
+			c.changeState(Connected, nil, i+1)
 			break
 		}
+
+		if _, ok := err.(AccessTokenError); ok {
+			c.changeState(Broken, err, i+1)
+			c.sendErr(err)
+			return err
+		}
+
+		if isUnauthorized(resp) {
+			err = newAccessTokenError("Access token rejected by websocket endpoint", err)
+			c.changeState(Broken, err, i+1)
+			c.sendErr(err)
+			return err
+		}
+
+		if c.logging() {
+			c.config.Logger.Warn("signalr dial failed", "endpoint", endpoint, "attempt", i+1, "err", err)
+		}
+
+		//@todo incorporate the currently ignored http response parameter into socketConnectionError
+		c.sendErr(
+			SocketConnectionError(
+				fmt.Sprintf(
+					"\n Unable to dial successfully: %s \n HTTP Response: %+v\n",
+					err.Error(),
+					resp,
+				),
+			),
+		)
 	}
 
 	return nil
 }
 
+// dialOnce make a single websocket dial attempt with a fresh access token and cache-busting parameter.
+func (c *client) dialOnce(
+	socketDialer *websocket.Dialer,
+	endpoint string,
+	query url.Values,
+	attempt int,
+) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	ctx, span := c.startSpan(SpanDial)
+	defer func() { endSpan(span, err) }()
+	if c.config.Tracer != nil {
+		span.SetAttribute(AttributeEndpoint, endpoint)
+		span.SetAttribute(AttributeAttempt, strconv.Itoa(attempt))
+	}
+
+	header := c.requestHeader()
+	attemptQuery := url.Values{}
+	for k, v := range query {
+		attemptQuery[k] = v
+	}
+	attemptQuery.Set("_", fmt.Sprintf("%d", time.Now().Unix()*1000))
+
+	if err = c.authorize(ctx, header, attemptQuery); err != nil {
+		return nil, err
+	}
+	c.injectTrace(ctx, header)
+
+	connectionURL := c.endpointURL(c.websocketScheme(), endpoint, attemptQuery)
+
+	if endpoint == c.config.ReconnectPath {
+		c.countReconnectAttempt()
+	}
+
+	if c.logging() {
+		c.config.Logger.Debug("signalr dialing", "url", logURL(connectionURL))
+	}
+
+	var socket *websocket.Conn
+	if socket, resp, err = socketDialer.DialContext(ctx, connectionURL.String(), header); err != nil {
+		return resp, err
+	}
+
+	//Reset while the handshake was in flight.  don't hand it a socket it has already torn down.
+	if err = c.context().Err(); err != nil {
+		socket.Close()
+		return resp, err
+	}
+
+	c.configureCompression(socket, resp.Header.Get("Sec-WebSocket-Extensions"))
+	c.setSocket(socket)
+
+	return resp, nil
+}
+
 func castHubNamesToString(hubs []string) []byte {
 	var connectionData = make([]struct {
 		Name string `json:"Name"`
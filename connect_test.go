@@ -2,19 +2,24 @@ package signalr
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 	//"fmt"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
 )
 
 func TestConnect(t *testing.T) {
 	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
 	cfg := Config{
 		Client: &http.Client{},
 		ConnectionURL: &url.URL{
-			Scheme: "https",
-			Host:   "socket.bittrex.com",
+			Scheme: "http",
+			Host:   server.URL().Host,
 		},
 		NegotiatePath: "signalr/negotiate",
 		ConnectPath:   "signalr/connect",
@@ -24,31 +29,33 @@ func TestConnect(t *testing.T) {
 	c := New(cfg).(*client)
 
 	timeout := time.NewTicker(time.Second * 30)
-	c.heartbeatChan = make(chan Heartbeat, 5)
+	errs := c.ListenToErrors()
+	heartbeats := c.ListenToHeartbeat()
 
 	//Act
 
-	c.Connect([]string{"c2"})
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
 	//assert
 	select {
-	case err := <-c.errChan:
+	case err := <-errs:
 		t.Fatalf("Error found!  %s\n", err.Error())
 	case <-timeout.C:
 		t.Fatalf("Timeout error")
-	case r := <-c.responseChan("default"):
-		t.Fatalf("what is r %+v", len(r.Data))
-	case <-c.heartbeatChan:
+	case <-heartbeats:
 	}
 
 }
 
 func TestNegotiate(t *testing.T) {
 	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
 	cfg := Config{
 		Client: &http.Client{},
 		ConnectionURL: &url.URL{
-			Scheme: "https",
-			Host:   "socket.bittrex.com",
+			Scheme: "http",
+			Host:   server.URL().Host,
 		},
 		NegotiatePath: "signalr/negotiate",
 		ConnectPath:   "signalr/connect",
@@ -58,11 +65,11 @@ func TestNegotiate(t *testing.T) {
 	c := New(cfg).(*client)
 
 	//act
-	nresp := c.negotiate()
+	nresp, err := c.negotiate()
 
 	//assert
 	if nresp == nil {
-		t.Errorf("unable to connect to sample server: %+v \n\n\n", nresp)
+		t.Errorf("unable to connect to sample server: %+v \n\n\n", err)
 	}
 }
 
@@ -83,15 +90,39 @@ func TestNegotiate(t *testing.T) {
 	c := New(cfg).(*client)
 	nresp := c.negotiate()
 
-	c.heartbeatChan = make(chan Heartbeat)
+	errs := c.ListenToErrors()
+	heartbeats := c.ListenToHeartbeat()
 
 	c.connectWebSocket(nresp, []string{"c2"})
 
 	select {
-	case e := <-c.errChan:
+	case e := <-errs:
 		t.Fatalf("error found! %s", e.Error())
-	case hb := <-c.heartbeatChan:
+	case hb := <-heartbeats:
 		t.Fatalf("HEARTBEAT %s", hb)
-	case <-c.responseChan("default"):
 	}
 }*/
+
+func TestNegotiatePlainHTTP(t *testing.T) {
+	//Assemble
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ConnectionToken":"abc","ProtocolVersion":"1.5","KeepAliveTimeout":20}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+
+	//Act
+	nresp, err := c.negotiate()
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unable to negotiate over plain http: %v", err)
+	}
+
+	if nresp.ConnectionToken != "abc" {
+		t.Errorf("unexpected connection token %q", nresp.ConnectionToken)
+	}
+}
@@ -1,9 +1,18 @@
 package signalr
 
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
 //Connection specify interface methods that allow consumer to interact with a connection type.
 type Connection interface {
 	State() ConnectionState
 	Connect([]string) error
+	Resume([]string) error
+	Reset()
 	CallHub(CallHubPayload, interface{}) error
 
 	ListenToErrors() <-chan error
@@ -11,5 +20,30 @@ type Connection interface {
 	ListenToHeartbeat() <-chan Heartbeat
 	SubscribeToState() <-chan ConnectionState
 
+	ErrorSubscription() (<-chan error, UnsubscribeFunc)
+	HubResponseSubscription() (<-chan MessageDataPayload, UnsubscribeFunc)
+	HeartbeatSubscription() (<-chan Heartbeat, UnsubscribeFunc)
+	HeartbeatEventSubscription() (<-chan HeartbeatEvent, UnsubscribeFunc)
+	StateSubscription() (<-chan ConnectionState, UnsubscribeFunc)
+	StateChangeSubscription() (<-chan StateChange, UnsubscribeFunc)
+
+	OnStateChanged(func(old ConnectionState, new ConnectionState)) UnsubscribeFunc
+	OnReconnecting(func()) UnsubscribeFunc
+	OnReconnected(func()) UnsubscribeFunc
+	OnClosed(func(error)) UnsubscribeFunc
+	OnError(func(error)) UnsubscribeFunc
+	OnReceived(func(json.RawMessage)) UnsubscribeFunc
+	OnHeartbeat(func(HeartbeatEvent)) UnsubscribeFunc
+	OnConnected(ConnectedHook) UnsubscribeFunc
+	OnGapDetected(func(GapDetected)) UnsubscribeFunc
+	OnConnectionSlow(func()) UnsubscribeFunc
+
+	SendPing() (time.Duration, error)
+	Replay(context.Context, io.Reader, float64) error
+
+	CompressionStats() CompressionStats
+	DroppedEvents() DroppedEvents
+	Stats() Stats
+
 
 }
@@ -0,0 +1,190 @@
+package signalr
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultCursorSaveInterval = time.Second
+
+// Cursor everything needed to reconnect to an existing signalr connection instead of negotiating a new one.
+type Cursor struct {
+	ConnectionToken   string    `json:"connection_token"`
+	ProtocolVersion   string    `json:"protocol_version"`
+	KeepAliveTimeout  float32   `json:"keep_alive_timeout"`
+	DisconnectTimeout float32   `json:"disconnect_timeout,omitempty"`
+	MessageID         string    `json:"message_id"`
+	GroupsToken       string    `json:"groups_token,omitempty"`
+	SavedAt           time.Time `json:"saved_at"`
+}
+
+// CursorStore persists the client's Cursor so a restarted process can Resume where the last one left off.
+type CursorStore interface {
+	//Load the last saved cursor.  Returns nil, nil when nothing has been saved yet.
+	Load(ctx context.Context) (*Cursor, error)
+	//Save replace the stored cursor.
+	Save(ctx context.Context, cursor Cursor) error
+}
+
+// FileCursorStore CursorStore keeping the cursor as JSON in a single file.  Saves are atomic: a crash mid-save leaves the
+// previous cursor intact.
+type FileCursorStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileCursorStore store the cursor at path.  The directory must already exist.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+// Load implement CursorStore
+func (fcs *FileCursorStore) Load(ctx context.Context) (*Cursor, error) {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(fcs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor Cursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// Save implement CursorStore
+func (fcs *FileCursorStore) Save(ctx context.Context, cursor Cursor) error {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fcs.path), filepath.Base(fcs.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), fcs.path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+// setNegotiated record the connection the cursor belongs to.  a new connection token invalidates the old cursor, and the
+// cursors seen on the old connection, which a restarted peer may well hand out again.
+func (c *client) setNegotiated(nResp *negotiationResponse) {
+	c.messageIDMutex.Lock()
+	defer c.messageIDMutex.Unlock()
+
+	if nResp == nil || c.negotiated == nil || c.negotiated.ConnectionToken != nResp.ConnectionToken {
+		c.messageID = ""
+		c.groupsToken = ""
+		c.cursors.reset()
+	}
+
+	c.negotiated = nResp
+}
+
+func (c *client) updateGroupsToken(token string) {
+	c.messageIDMutex.Lock()
+	c.groupsToken = token
+	c.messageIDMutex.Unlock()
+
+	c.saveCursor(c.context(), false)
+}
+
+// cursor snapshot of the current resume point.  false if the client hasn't negotiated a connection.
+func (c *client) cursor() (Cursor, bool) {
+	c.messageIDMutex.Lock()
+	defer c.messageIDMutex.Unlock()
+
+	if c.negotiated == nil {
+		return Cursor{}, false
+	}
+
+	return Cursor{
+		ConnectionToken:   c.negotiated.ConnectionToken,
+		ProtocolVersion:   c.negotiated.ProtocolVersion,
+		KeepAliveTimeout:  c.negotiated.KeepAliveTimeout,
+		DisconnectTimeout: c.negotiated.DisconnectTimeout,
+		MessageID:         c.messageID,
+		GroupsToken:       c.groupsToken,
+	}, true
+}
+
+// restoreCursor adopt a stored cursor as the current resume point.
+func (c *client) restoreCursor(cursor Cursor) *negotiationResponse {
+	nResp := &negotiationResponse{
+		ConnectionToken:   cursor.ConnectionToken,
+		ProtocolVersion:   cursor.ProtocolVersion,
+		KeepAliveTimeout:  cursor.KeepAliveTimeout,
+		DisconnectTimeout: cursor.DisconnectTimeout,
+	}
+
+	c.messageIDMutex.Lock()
+	defer c.messageIDMutex.Unlock()
+
+	c.negotiated = nResp
+	c.messageID = cursor.MessageID
+	c.groupsToken = cursor.GroupsToken
+
+	return nResp
+}
+
+// saveCursor write the cursor to the configured store.  unless forced, saves are throttled to one per CursorSaveInterval,
+// so a crash can cost at most that much replay (never a gap) on Resume.
+func (c *client) saveCursor(ctx context.Context, force bool) {
+	if c.config.CursorStore == nil {
+		return
+	}
+
+	cursor, ok := c.cursor()
+	if !ok {
+		return
+	}
+
+	c.cursorSaveMutex.Lock()
+	defer c.cursorSaveMutex.Unlock()
+
+	now := time.Now()
+	if !force && now.Sub(c.cursorSavedAt) < c.config.CursorSaveInterval {
+		return
+	}
+
+	cursor.SavedAt = now
+	if err := c.config.CursorStore.Save(ctx, cursor); err != nil {
+		c.sendErr(newCursorStoreError("Unable to save cursor", err))
+		return
+	}
+
+	c.cursorSavedAt = now
+}
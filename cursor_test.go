@@ -0,0 +1,197 @@
+package signalr
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryCursorStore struct {
+	mutex  sync.Mutex
+	cursor *Cursor
+	saves  int
+}
+
+func (mcs *memoryCursorStore) Load(ctx context.Context) (*Cursor, error) {
+	mcs.mutex.Lock()
+	defer mcs.mutex.Unlock()
+
+	return mcs.cursor, nil
+}
+
+func (mcs *memoryCursorStore) Save(ctx context.Context, cursor Cursor) error {
+	mcs.mutex.Lock()
+	defer mcs.mutex.Unlock()
+
+	mcs.cursor = &cursor
+	mcs.saves++
+	return nil
+}
+
+func (mcs *memoryCursorStore) load() Cursor {
+	mcs.mutex.Lock()
+	defer mcs.mutex.Unlock()
+
+	if mcs.cursor == nil {
+		return Cursor{}
+	}
+	return *mcs.cursor
+}
+
+func TestFileCursorStore(t *testing.T) {
+	//Assemble
+	dir, err := ioutil.TempDir("", "signalr-cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileCursorStore(filepath.Join(dir, "cursor.json"))
+
+	//Act
+	missing, err := store.Load(context.Background())
+	if err != nil || missing != nil {
+		t.Fatalf("expected nil, nil before first save, found %+v, %v", missing, err)
+	}
+
+	saved := Cursor{ConnectionToken: "abc", ProtocolVersion: "1.5", MessageID: "d-1,2", GroupsToken: "grp"}
+	if err = store.Save(context.Background(), saved); err != nil {
+		t.Fatalf("unexpected save error: %v", err)
+	}
+
+	loaded, err := store.Load(context.Background())
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+
+	if *loaded != saved {
+		t.Errorf("expected %+v, loaded %+v", saved, *loaded)
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected only the cursor file to remain, found %d files", len(files))
+	}
+}
+
+func TestConnectSavesCursor(t *testing.T) {
+	//Assemble
+	requests := make(chan *http.Request, 10)
+	server := newPeerServer(peerConfig{frames: []string{`{"G":"grp"}`}, requests: requests})
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	store := &memoryCursorStore{}
+	c := New(Config{ConnectionURL: serverURL, CursorStore: store, CursorSaveInterval: time.Nanosecond}).(*client)
+
+	//Act
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+
+	//Assert
+	deadline := time.After(5 * time.Second)
+	for store.load().GroupsToken != "grp" {
+		select {
+		case <-deadline:
+			t.Fatalf("cursor never saved with groups token, found %+v", store.load())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if cursor := store.load(); cursor.ConnectionToken != "fresh" || cursor.ProtocolVersion != "1.5" {
+		t.Errorf("unexpected saved cursor %+v", cursor)
+	}
+}
+
+func TestResumeReconnectsWithStoredCursor(t *testing.T) {
+	//Assemble
+	requests := make(chan *http.Request, 10)
+	server := newPeerServer(peerConfig{requests: requests})
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	store := &memoryCursorStore{cursor: &Cursor{
+		ConnectionToken:  "stored",
+		ProtocolVersion:  "1.5",
+		KeepAliveTimeout: 20,
+		MessageID:        "d-1,2",
+		GroupsToken:      "grp",
+	}}
+	c := New(Config{ConnectionURL: serverURL, CursorStore: store}).(*client)
+	states := c.SubscribeToState()
+
+	//Act
+	go c.Resume([]string{"c2"})
+	defer c.Reset()
+
+	//Assert
+	waitForState(t, states, Connected)
+
+	r := <-requests
+	if !strings.HasSuffix(r.URL.Path, reconnectPath) {
+		t.Fatalf("expected first request to reconnect, found %s", r.URL.Path)
+	}
+
+	query := r.URL.Query()
+	if query.Get("connectionToken") != "stored" || query.Get("messageId") != "d-1,2" || query.Get("groupsToken") != "grp" {
+		t.Errorf("stored cursor not sent on reconnect: %s", r.URL.RawQuery)
+	}
+}
+
+func TestResumeFallsBackWhenCursorRejected(t *testing.T) {
+	//Assemble
+	requests := make(chan *http.Request, 10)
+	server := newPeerServer(peerConfig{rejectReconnect: true, requests: requests})
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	store := &memoryCursorStore{cursor: &Cursor{ConnectionToken: "stale", ProtocolVersion: "1.5", MessageID: "d-1,2"}}
+	c := New(Config{ConnectionURL: serverURL, CursorStore: store}).(*client)
+	states := c.SubscribeToState()
+	errs := c.ListenToErrors()
+
+	//Act
+	go c.Resume([]string{"c2"})
+	defer c.Reset()
+
+	//Assert
+	waitForState(t, states, Connected)
+
+	select {
+	case err := <-errs:
+		if _, ok := err.(ResumeError); !ok {
+			t.Errorf("expected ResumeError, received %T", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout waiting for ResumeError")
+	}
+
+	var paths []string
+	for len(requests) > 0 {
+		paths = append(paths, (<-requests).URL.Path)
+	}
+
+	if len(paths) != 3 || !strings.HasSuffix(paths[1], negotiatePath) || !strings.HasSuffix(paths[2], connectPath) {
+		t.Errorf("expected reconnect, negotiate, connect; found %v", paths)
+	}
+
+	if cursor := store.load(); cursor.ConnectionToken != "fresh" || cursor.MessageID != "" {
+		t.Errorf("expected the fresh connection to replace the stale cursor, found %+v", cursor)
+	}
+}
+
+func TestResumeWithoutStore(t *testing.T) {
+	c := New(Config{})
+
+	if _, ok := c.Resume([]string{"c2"}).(ConnectError); !ok {
+		t.Error("expected ConnectError without a CursorStore")
+	}
+}
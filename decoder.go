@@ -0,0 +1,124 @@
+package signalr
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ArgumentDecoder transforms a raw hub argument, or CallHub result, before it reaches the consumer.
+type ArgumentDecoder func(json.RawMessage) (json.RawMessage, error)
+
+// DecoderRule runs Decoders, in order, over arguments and results from a matching hub and method.
+// An empty Hub or Method matches anything.  Names are compared case insensitively, as signalr does.
+// A null argument or result, e.g. from a method returning void, is passed through without decoding.
+type DecoderRule struct {
+	Hub      string
+	Method   string
+	Decoders []ArgumentDecoder
+}
+
+func (dr DecoderRule) matches(hub string, method string) bool {
+	return (dr.Hub == "" || strings.EqualFold(dr.Hub, hub)) &&
+		(dr.Method == "" || strings.EqualFold(dr.Method, method))
+}
+
+// IdentityDecoder passes the raw argument through untouched.  Useful to exempt a method from a broader rule.
+func IdentityDecoder(raw json.RawMessage) (json.RawMessage, error) {
+	return raw, nil
+}
+
+// Base64DeflateDecoder decodes a JSON string holding base64-encoded raw deflate data, yielding the inflated JSON.
+func Base64DeflateDecoder(raw json.RawMessage) (json.RawMessage, error) {
+	return inflateBase64(raw, func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	})
+}
+
+// Base64GzipDecoder decodes a JSON string holding base64-encoded gzip data, yielding the inflated JSON.
+func Base64GzipDecoder(raw json.RawMessage) (json.RawMessage, error) {
+	return inflateBase64(raw, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+func inflateBase64(raw json.RawMessage, newReader func(io.Reader) (io.ReadCloser, error)) (json.RawMessage, error) {
+	var (
+		encoded    string
+		compressed []byte
+		inflated   []byte
+		reader     io.ReadCloser
+		err        error
+	)
+
+	if err = json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+
+	if compressed, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return nil, err
+	}
+
+	if reader, err = newReader(bytes.NewReader(compressed)); err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if inflated, err = ioutil.ReadAll(reader); err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(inflated) {
+		return nil, errors.New("inflated payload is not valid JSON")
+	}
+
+	return json.RawMessage(inflated), nil
+}
+
+// decoders the decoder chain for the first rule matching hub and method.  nil if nothing matches.
+func (c *client) decoders(hub string, method string) []ArgumentDecoder {
+	for _, rule := range c.config.ArgumentDecoders {
+		if rule.matches(hub, method) {
+			return rule.Decoders
+		}
+	}
+
+	return nil
+}
+
+// decode run raw through the decoder chain configured for hub and method.  null has nothing to decode.
+func (c *client) decode(hub string, method string, raw json.RawMessage) (json.RawMessage, error) {
+	var err error
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return raw, nil
+	}
+
+	for _, decoder := range c.decoders(hub, method) {
+		if raw, err = decoder(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return raw, nil
+}
+
+// decodeArguments run each of the payload's arguments through its decoder chain, in place.
+func (c *client) decodeArguments(payload *MessageDataPayload) error {
+	for i := range payload.Arguments {
+		decoded, err := c.decode(payload.HubName, payload.Method, payload.Arguments[i])
+		if err != nil {
+			return err
+		}
+
+		payload.Arguments[i] = decoded
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package signalr
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+func deflated(payload string) json.RawMessage {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write([]byte(payload))
+	w.Close()
+
+	encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return encoded
+}
+
+func gzipped(payload string) json.RawMessage {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(payload))
+	w.Close()
+
+	encoded, _ := json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return encoded
+}
+
+func TestBuiltinDecoders(t *testing.T) {
+	payload := `{"MarketName":"BTC-ETH","Nonce":42}`
+
+	cases := []struct {
+		name    string
+		decoder ArgumentDecoder
+		input   json.RawMessage
+	}{
+		{"identity", IdentityDecoder, json.RawMessage(payload)},
+		{"deflate", Base64DeflateDecoder, deflated(payload)},
+		{"gzip", Base64GzipDecoder, gzipped(payload)},
+	}
+
+	for _, tc := range cases {
+		decoded, err := tc.decoder(tc.input)
+		if err != nil {
+			t.Errorf("%s: unexpected decode error %v", tc.name, err)
+			continue
+		}
+
+		if string(decoded) != payload {
+			t.Errorf("%s: expected %s, found %s", tc.name, payload, string(decoded))
+		}
+	}
+}
+
+func TestBuiltinDecodersRejectGarbage(t *testing.T) {
+	inputs := []json.RawMessage{
+		json.RawMessage(`42`),
+		json.RawMessage(`"not base64!"`),
+		json.RawMessage(`"` + base64.StdEncoding.EncodeToString([]byte("not compressed")) + `"`),
+	}
+
+	for _, input := range inputs {
+		if _, err := Base64DeflateDecoder(input); err == nil {
+			t.Errorf("deflate decoder accepted %s", string(input))
+		}
+
+		if _, err := Base64GzipDecoder(input); err == nil {
+			t.Errorf("gzip decoder accepted %s", string(input))
+		}
+	}
+}
+
+func TestDecoderRuleMatching(t *testing.T) {
+	c := New(Config{
+		ArgumentDecoders: []DecoderRule{
+			{Hub: "c2", Method: "uS", Decoders: []ArgumentDecoder{IdentityDecoder}},
+			{Hub: "C2", Decoders: []ArgumentDecoder{Base64DeflateDecoder}},
+			{Decoders: []ArgumentDecoder{Base64GzipDecoder, IdentityDecoder}},
+		},
+	}).(*client)
+
+	cases := []struct {
+		hub, method string
+		expected    int
+	}{
+		{"c2", "us", 1},
+		{"c2", "uE", 1},
+		{"other", "uE", 2},
+	}
+
+	for _, tc := range cases {
+		if got := len(c.decoders(tc.hub, tc.method)); got != tc.expected {
+			t.Errorf("%s.%s: expected chain of %d decoders, found %d", tc.hub, tc.method, tc.expected, got)
+		}
+	}
+}
+
+func TestDispatchMessageDecodesArguments(t *testing.T) {
+	//Assemble
+	payload := `{"Deltas":[1,2,3]}`
+
+	c := New(Config{
+		ArgumentDecoders: []DecoderRule{
+			{Hub: "c2", Method: "uE", Decoders: []ArgumentDecoder{Base64DeflateDecoder}},
+		},
+	}).(*client)
+
+	data, _ := json.Marshal(MessageDataPayload{
+		HubName:   "C2",
+		Method:    "uE",
+		Arguments: []json.RawMessage{deflated(payload)},
+	})
+
+	//Act
+	go c.dispatchMessage(serverMessage{Identifier: "9", Data: []json.RawMessage{data}})
+
+	//Assert
+	select {
+	case msg := <-c.ListenToHubResponses():
+		if len(msg.Arguments) != 1 || string(msg.Arguments[0]) != payload {
+			t.Errorf("expected decoded argument %s, found %+v", payload, msg.Arguments)
+		}
+	case err := <-c.ListenToErrors():
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for decoded message")
+	}
+}
+
+func TestCallHubNullResultSkipsDecoders(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+	server.Handle("c2", "SubscribeToExchangeDeltas", func(args []json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+
+	c := New(Config{
+		ConnectionURL: server.URL(),
+		ArgumentDecoders: []DecoderRule{
+			{Hub: "c2", Decoders: []ArgumentDecoder{Base64DeflateDecoder}},
+		},
+	}).(*client)
+	states := c.SubscribeToState()
+
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	waitForState(t, states, Connected)
+
+	//Act
+	result := &struct{ Subscribed bool }{}
+	err := c.CallHub(CallHubPayload{Hub: "c2", Method: "SubscribeToExchangeDeltas", Arguments: []interface{}{"BTC-ETH"}}, &result)
+
+	//Assert
+	if err != nil {
+		t.Errorf("expected a null result to bypass the deflate decoder, found %v", err)
+	}
+
+	if result != nil {
+		t.Errorf("expected the null result to reach the caller, found %+v", result)
+	}
+}
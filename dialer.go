@@ -0,0 +1,102 @@
+package signalr
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// default handshake timeouts, used when Config.HandshakeTimeout is not set.
+const (
+	connectHandshakeTimeout   time.Duration = 45 * time.Second
+	reconnectHandshakeTimeout time.Duration = 30 * time.Second
+)
+
+// newDialer build the websocket dialer from the sanitized config.  Anything not set explicitly in the config is
+// borrowed from the http.Client's transport, so negotiate and connect reach the peer the same way.
+func (c *client) newDialer(handshakeTimeout time.Duration) *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		NetDialContext:    c.config.NetDialContext,
+		Proxy:             c.config.Proxy,
+		TLSClientConfig:   websocketTLSConfig(c.config.TLSClientConfig),
+		HandshakeTimeout:  handshakeTimeout,
+		ReadBufferSize:    c.config.ReadBufferSize,
+		WriteBufferSize:   c.config.WriteBufferSize,
+		Subprotocols:      c.config.Subprotocols,
+		EnableCompression: c.config.EnableCompression,
+		Jar:               c.config.Client.Jar,
+	}
+
+	if c.config.HandshakeTimeout > 0 {
+		dialer.HandshakeTimeout = c.config.HandshakeTimeout
+	}
+
+	if transport := httpTransport(c.config.Client); transport != nil {
+		if dialer.NetDialContext == nil {
+			dialer.NetDialContext = transport.DialContext
+		}
+
+		if dialer.Proxy == nil {
+			dialer.Proxy = transport.Proxy
+		}
+
+		if dialer.TLSClientConfig == nil && transport.TLSClientConfig != nil {
+			dialer.TLSClientConfig = websocketTLSConfig(transport.TLSClientConfig)
+		}
+	}
+
+	dialer.NetDialContext = countingDialContext(dialer.NetDialContext, c.bytes)
+
+	return dialer
+}
+
+// websocketTLSConfig a copy of config for the websocket dialer.  The upgrade needs HTTP/1.1, so that's all ALPN may
+// offer; an h2 capable peer would otherwise pick HTTP/2 and reject the handshake.
+func websocketTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		return nil
+	}
+
+	config = config.Clone()
+	config.NextProtos = []string{"http/1.1"}
+
+	return config
+}
+
+// httpTransport the *http.Transport used by the client, if that's what it is.  nil for custom RoundTrippers.
+func httpTransport(hc *http.Client) *http.Transport {
+	if hc.Transport == nil {
+		transport, _ := http.DefaultTransport.(*http.Transport)
+		return transport
+	}
+
+	transport, _ := hc.Transport.(*http.Transport)
+	return transport
+}
+
+// newHTTPClient default http client for a config without one.  If the config carries TLS, dial or proxy settings they
+// are applied to the transport too, so negotiation works against the same mTLS-only peer as the websocket.
+func newHTTPClient(c Config) *http.Client {
+	if c.TLSClientConfig == nil && c.NetDialContext == nil && c.Proxy == nil {
+		return &http.Client{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.TLSClientConfig != nil {
+		//a copy: the transport adds h2 to NextProtos, which mustn't leak into the consumer's config or the dialer.
+		transport.TLSClientConfig = c.TLSClientConfig.Clone()
+	}
+
+	if c.NetDialContext != nil {
+		transport.DialContext = c.NetDialContext
+	}
+
+	if c.Proxy != nil {
+		transport.Proxy = c.Proxy
+	}
+
+	return &http.Client{Transport: transport}
+}
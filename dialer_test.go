@@ -0,0 +1,158 @@
+package signalr
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+func TestNewDialerDefaultsFromTransport(t *testing.T) {
+	//Assemble
+	tlsConfig := &tls.Config{ServerName: "from.transport"}
+	proxy := func(*http.Request) (*url.URL, error) { return nil, nil }
+
+	c := New(Config{
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				Proxy:           proxy,
+			},
+		},
+	}).(*client)
+
+	//Act
+	dialer := c.newDialer(connectHandshakeTimeout)
+
+	//Assert
+	if dialer.TLSClientConfig == nil || dialer.TLSClientConfig.ServerName != "from.transport" {
+		t.Errorf("expected tls config borrowed from transport, found %+v", dialer.TLSClientConfig)
+	}
+
+	if dialer.Proxy == nil {
+		t.Errorf("expected proxy borrowed from transport")
+	}
+
+	if dialer.HandshakeTimeout != connectHandshakeTimeout {
+		t.Errorf("expected default handshake timeout %s, found %s", connectHandshakeTimeout, dialer.HandshakeTimeout)
+	}
+}
+
+func TestNewDialerExplicitConfig(t *testing.T) {
+	//Assemble
+	dialErr := errors.New("custom dialer")
+
+	cfg := Config{
+		TLSClientConfig: &tls.Config{ServerName: "from.config"},
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, dialErr
+		},
+		HandshakeTimeout: time.Second,
+		ReadBufferSize:   2048,
+		WriteBufferSize:  4096,
+		Subprotocols:     []string{"signalr"},
+	}
+
+	c := New(cfg).(*client)
+
+	//Act
+	dialer := c.newDialer(reconnectHandshakeTimeout)
+
+	//Assert
+	if dialer.TLSClientConfig.ServerName != "from.config" {
+		t.Errorf("expected explicit tls config, found %+v", dialer.TLSClientConfig)
+	}
+
+	if _, err := dialer.NetDialContext(context.Background(), "tcp", "localhost:1"); err != dialErr {
+		t.Errorf("expected explicit dial function, received %v", err)
+	}
+
+	if dialer.HandshakeTimeout != time.Second {
+		t.Errorf("expected configured handshake timeout, found %s", dialer.HandshakeTimeout)
+	}
+
+	if dialer.ReadBufferSize != 2048 || dialer.WriteBufferSize != 4096 {
+		t.Errorf("unexpected buffer sizes %d/%d", dialer.ReadBufferSize, dialer.WriteBufferSize)
+	}
+
+	if len(dialer.Subprotocols) != 1 || dialer.Subprotocols[0] != "signalr" {
+		t.Errorf("unexpected subprotocols %+v", dialer.Subprotocols)
+	}
+
+	//the default http client should share the same settings so negotiate reaches the same peer.
+	transport := httpTransport(c.config.Client)
+	if transport == nil || transport.TLSClientConfig.ServerName != "from.config" {
+		t.Errorf("expected default http client to carry the tls config")
+	}
+}
+
+func TestDialWebSocketWithTransportTLS(t *testing.T) {
+	//Assemble
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	//server.Client() trusts the test server's certificate; the dialer must pick that up.
+	c := New(Config{
+		Client:        server.Client(),
+		ConnectionURL: serverURL,
+	}).(*client)
+	c.setState(Connecting)
+
+	//Act
+	err := c.dialWebSocket(c.config.ConnectPath, url.Values{}, time.Second, time.Time{})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unable to dial tls test server: %v", err)
+	}
+
+	if c.State() != Connected {
+		t.Errorf("expected connected state, found %d", c.State())
+	}
+}
+
+func TestConnectTLSWithHTTP2Server(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{TLS: true})
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tlsConfig := &tls.Config{RootCAs: roots}
+
+	c := New(Config{ConnectionURL: server.URL(), TLSClientConfig: tlsConfig}).(*client)
+	states := c.SubscribeToState()
+
+	//Act
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+
+	//Assert
+	waitForState(t, states, Connected)
+
+	if len(tlsConfig.NextProtos) != 0 {
+		t.Errorf("expected the consumer's tls config untouched, found NextProtos %v", tlsConfig.NextProtos)
+	}
+
+	if protos := c.newDialer(connectHandshakeTimeout).TLSClientConfig.NextProtos; len(protos) != 1 || protos[0] != "http/1.1" {
+		t.Errorf("expected the dialer to offer only http/1.1, found %v", protos)
+	}
+}
@@ -0,0 +1,27 @@
+package signalr
+
+import (
+	"net/url"
+	"path"
+)
+
+// endpointURL build the url for a signalr endpoint.  The endpoint is joined to the base path of ConnectionURL, and the
+// query combines ConnectionURL's own query, the configured QueryString, then the protocol parameters, later values winning.
+func (c *client) endpointURL(scheme string, endpoint string, query url.Values) url.URL {
+	merged := c.config.ConnectionURL.Query()
+
+	for k, v := range c.config.QueryString {
+		merged.Set(k, v)
+	}
+
+	for k, v := range query {
+		merged[k] = v
+	}
+
+	return url.URL{
+		Scheme:   scheme,
+		Host:     c.config.ConnectionURL.Host,
+		Path:     path.Join("/", c.config.ConnectionURL.Path, endpoint),
+		RawQuery: merged.Encode(),
+	}
+}
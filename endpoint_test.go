@@ -0,0 +1,66 @@
+package signalr
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEndpointURL(t *testing.T) {
+	cases := []struct {
+		connectionURL string
+		queryString   map[string]string
+		endpoint      string
+		query         url.Values
+		expected      string
+	}{
+		{
+			connectionURL: "https://peer.example",
+			endpoint:      "negotiate",
+			expected:      "https://peer.example/negotiate",
+		},
+		{
+			connectionURL: "https://peer.example/api/signalr",
+			endpoint:      "negotiate",
+			expected:      "https://peer.example/api/signalr/negotiate",
+		},
+		{
+			connectionURL: "https://peer.example/api/signalr/",
+			endpoint:      "/connect",
+			expected:      "https://peer.example/api/signalr/connect",
+		},
+		{
+			connectionURL: "https://peer.example/api?apikey=s3cret",
+			endpoint:      "signalr/reconnect",
+			query:         url.Values{"transport": []string{"webSockets"}},
+			expected:      "https://peer.example/api/signalr/reconnect?apikey=s3cret&transport=webSockets",
+		},
+		{
+			connectionURL: "https://peer.example?tenant=a",
+			queryString:   map[string]string{"tenant": "b", "region": "eu"},
+			endpoint:      "negotiate",
+			expected:      "https://peer.example/negotiate?region=eu&tenant=b",
+		},
+		{
+			connectionURL: "https://peer.example",
+			queryString:   map[string]string{"clientProtocol": "0.1"},
+			endpoint:      "negotiate",
+			query:         url.Values{"clientProtocol": []string{"1.5"}},
+			expected:      "https://peer.example/negotiate?clientProtocol=1.5",
+		},
+	}
+
+	for _, tc := range cases {
+		connURL, err := url.Parse(tc.connectionURL)
+		if err != nil {
+			t.Fatalf("unable to parse test url: %s", err.Error())
+		}
+
+		c := New(Config{ConnectionURL: connURL, QueryString: tc.queryString}).(*client)
+
+		got := c.endpointURL(c.httpScheme(), tc.endpoint, tc.query)
+
+		if got.String() != tc.expected {
+			t.Errorf("expected %s, found %s", tc.expected, got.String())
+		}
+	}
+}
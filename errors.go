@@ -1,6 +1,9 @@
 package signalr
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ConnectError used when consuming app tries to connect when app is in broken state.
 type ConnectError string
@@ -18,10 +21,10 @@ type baseError struct {
 
 func (b baseError) Error() string {
 	return fmt.Sprintf(
-		"%s \n Source: %s \n %Error: s",
+		"%s \n Source: %s \n Error: %v",
 		b.prefix,
 		b.source,
-		b.err.Error(),
+		b.err,
 	)
 }
 
@@ -46,6 +49,29 @@ func NewNegotiationError(source string, err error) NegotiationError {
 	)
 }
 
+// Error implement Error interface
+func (ne NegotiationError) Error() string {
+	return baseError(ne).Error()
+}
+
+// AccessTokenError error created when the configured AccessTokenProvider fails, or the peer rejects its token.
+type AccessTokenError baseError
+
+func newAccessTokenError(source string, err error) AccessTokenError {
+	return AccessTokenError(
+		newBaseError(
+			"AccessTokenError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (ate AccessTokenError) Error() string {
+	return baseError(ate).Error()
+}
+
 //SocketConnectionError error created when connectWebSocket step of connection fails.
 type SocketConnectionError string
 
@@ -57,7 +83,8 @@ func (sce SocketConnectionError) Error() string {
 //SocketError error created when websocket.ReadMessage or websocket.WriteMessage returns an error..
 type SocketError baseError
 
-func newSocketError(source string, err error) SocketError {
+// NewSocketError wrap err from reading or writing the socket, as the client does.  For fakes of Connection.
+func NewSocketError(source string, err error) SocketError {
 	return SocketError(
 		newBaseError(
 			"SocketError",
@@ -67,6 +94,11 @@ func newSocketError(source string, err error) SocketError {
 	)
 }
 
+// Error implement Error interface
+func (se SocketError) Error() string {
+	return baseError(se).Error()
+}
+
 //TimeoutError error created when dispatch loop times out
 type TimeoutError string
 
@@ -86,7 +118,8 @@ func (hme HubMessageError) Error() string {
 // CallHubError error generated during an attempt to send a message to the Signalr hub
 type CallHubError baseError
 
-func newCallHubError(source string, err error) CallHubError {
+// NewCallHubError wrap err from a failed CallHub, as the client does.  For fakes of Connection.
+func NewCallHubError(source string, err error) CallHubError {
 	return CallHubError(
 		newBaseError(
 			"CallHubError",
@@ -96,6 +129,11 @@ func newCallHubError(source string, err error) CallHubError {
 	)
 }
 
+// Error implement Error interface
+func (che CallHubError) Error() string {
+	return baseError(che).Error()
+}
+
 type MDPParseError baseError
 
 func newMDPParseError(source string, err error) MDPParseError {
@@ -107,6 +145,164 @@ func newMDPParseError(source string, err error) MDPParseError {
 	)
 }
 
+// Error implement Error interface
+func (mpe MDPParseError) Error() string {
+	return baseError(mpe).Error()
+}
+
+// ArgumentDecodeError error created when a configured ArgumentDecoder fails on a hub argument or CallHub result.
+type ArgumentDecodeError baseError
+
+func newArgumentDecodeError(source string, err error) ArgumentDecodeError {
+	return ArgumentDecodeError(
+		newBaseError(
+			"ArgumentDecodeError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (ade ArgumentDecodeError) Error() string {
+	return baseError(ade).Error()
+}
+
+// ConnectedHookError error created when an OnConnected hook still fails after its retries.
+type ConnectedHookError baseError
+
+func newConnectedHookError(source string, err error) ConnectedHookError {
+	return ConnectedHookError(
+		newBaseError(
+			"ConnectedHookError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (che ConnectedHookError) Error() string {
+	return baseError(che).Error()
+}
+
+// CursorStoreError error created when the configured CursorStore fails to load or save the cursor.
+type CursorStoreError baseError
+
+func newCursorStoreError(source string, err error) CursorStoreError {
+	return CursorStoreError(
+		newBaseError(
+			"CursorStoreError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (cse CursorStoreError) Error() string {
+	return baseError(cse).Error()
+}
+
+// ResumeError error created when the peer rejects a stored cursor and Resume falls back to a fresh connection.
+type ResumeError baseError
+
+func newResumeError(source string, err error) ResumeError {
+	return ResumeError(
+		newBaseError(
+			"ResumeError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (re ResumeError) Error() string {
+	return baseError(re).Error()
+}
+
+// ReplayError error created when a frame recording can't be read back.
+type ReplayError baseError
+
+func newReplayError(source string, err error) ReplayError {
+	return ReplayError(
+		newBaseError(
+			"ReplayError",
+			source,
+			err,
+		),
+	)
+}
+
+// Error implement Error interface
+func (re ReplayError) Error() string {
+	return baseError(re).Error()
+}
+
+// GapDetected reported when the client couldn't pick its connection back up from the message cursor and fell back to a
+// freshly negotiated one.  Anything the peer sent after LastMessageID is lost, so resync from a snapshot.
+type GapDetected struct {
+	//LastMessageID the last cursor received before the gap.  Empty if nothing had been received.
+	LastMessageID string
+	//Cause the error that prevented resuming.
+	Cause error
+	At    time.Time
+}
+
+// Error implement Error interface
+func (gd GapDetected) Error() string {
+	return fmt.Sprintf("GapDetected: fell back to a new connection after message %q: %v", gd.LastMessageID, gd.Cause)
+}
+
+// ConnectionSlow reported when nothing, not even a keepalive, has arrived from the peer for 2/3 of the keepalive timeout.
+// If the silence lasts the full timeout, the socket is dropped and reconnected.
+type ConnectionSlow struct {
+	Silence          time.Duration
+	KeepAliveTimeout time.Duration
+}
+
+// Error implement Error interface
+func (cs ConnectionSlow) Error() string {
+	return fmt.Sprintf("ConnectionSlow: no data from peer for %s (keepalive timeout %s)", cs.Silence, cs.KeepAliveTimeout)
+}
+
+// MessageDroppedError error created when the hub response channel overflows and a message is discarded.
+type MessageDroppedError struct {
+	Policy OverflowPolicy
+	//Dropped total messages discarded since the client was created.
+	Dropped uint64
+}
+
+// Error implement Error interface
+func (mde MessageDroppedError) Error() string {
+	return fmt.Sprintf("MessageDroppedError: hub response channel full (policy %s), %d messages dropped", mde.Policy, mde.Dropped)
+}
+
+// CallbackPanicError error created when a registered callback panics.  The panic is recovered so the client keeps running.
+type CallbackPanicError struct {
+	//Callback the registration method of the handler that panicked, e.g. "OnReceived".
+	Callback string
+	//Recovered the recovered panic value, formatted.
+	Recovered string
+}
+
+// Error implement Error interface
+func (cpe CallbackPanicError) Error() string {
+	return fmt.Sprintf("CallbackPanicError: %s handler panicked: %s", cpe.Callback, cpe.Recovered)
+}
+
+// InvalidTransitionError error created when a state change the connection state machine doesn't allow is attempted.
+type InvalidTransitionError struct {
+	From ConnectionState
+	To   ConnectionState
+}
+
+// Error implement Error interface
+func (ite InvalidTransitionError) Error() string {
+	return fmt.Sprintf("InvalidTransitionError: cannot move from %s to %s", ite.From, ite.To)
+}
+
 
 
 // BrokenWebSocketError describes a broken websocket error
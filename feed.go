@@ -0,0 +1,144 @@
+package signalr
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// UnsubscribeFunc stops delivery to a subscription and closes its channel.  Safe to call more than once.
+type UnsubscribeFunc func()
+
+// deliverFunc hands a single event to one subscriber.  done is closed when the subscriber goes away, so a delivery that
+// waits on a slow consumer must also select on it.
+type deliverFunc func(event interface{}, done <-chan struct{})
+
+type subscriber struct {
+	deliver deliverFunc
+	close   func()
+	done    chan struct{}
+}
+
+// feed fans a stream of events out to any number of independent subscribers.
+type feed struct {
+	mutex       sync.RWMutex
+	nextID      int
+	subscribers map[int]*subscriber
+}
+
+func newFeed() *feed {
+	return &feed{
+		subscribers: map[int]*subscriber{},
+	}
+}
+
+// subscribe register a subscriber.  closeFn is called once, after the subscriber can no longer receive deliveries.
+func (f *feed) subscribe(deliver deliverFunc, closeFn func()) UnsubscribeFunc {
+	s := &subscriber{
+		deliver: deliver,
+		close:   closeFn,
+		done:    make(chan struct{}),
+	}
+
+	f.mutex.Lock()
+	id := f.nextID
+	f.nextID++
+	f.subscribers[id] = s
+	f.mutex.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			//release a publish blocked on this subscriber before waiting for the write lock.
+			close(s.done)
+
+			f.mutex.Lock()
+			delete(f.subscribers, id)
+			f.mutex.Unlock()
+
+			s.close()
+		})
+	}
+}
+
+// publish deliver event to every current subscriber.
+func (f *feed) publish(event interface{}) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for _, s := range f.subscribers {
+		s.deliver(event, s.done)
+	}
+}
+
+// ErrorSubscription a new, independent error channel.  Errors are dropped and counted once its buffer is full.
+func (c *client) ErrorSubscription() (<-chan error, UnsubscribeFunc) {
+	ch := make(chan error, c.config.ErrorBufferSize)
+
+	unsubscribe := c.errFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			select {
+			case ch <- event.(error):
+			default:
+				atomic.AddUint64(&c.dropped.errors, 1)
+				c.countDrop("errors")
+			}
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
+
+// HeartbeatSubscription a new, independent heartbeat channel.  Heartbeats are dropped and counted once its buffer is full.
+func (c *client) HeartbeatSubscription() (<-chan Heartbeat, UnsubscribeFunc) {
+	ch := make(chan Heartbeat, c.config.HeartbeatBufferSize)
+
+	unsubscribe := c.heartbeatFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			select {
+			case ch <- event.(HeartbeatEvent).Legacy():
+			default:
+				atomic.AddUint64(&c.dropped.heartbeats, 1)
+				c.countDrop("heartbeats")
+			}
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
+
+// HeartbeatEventSubscription a new, independent channel of structured heartbeats.  Dropped and counted like
+// HeartbeatSubscription once its buffer is full.
+func (c *client) HeartbeatEventSubscription() (<-chan HeartbeatEvent, UnsubscribeFunc) {
+	ch := make(chan HeartbeatEvent, c.config.HeartbeatBufferSize)
+
+	unsubscribe := c.heartbeatFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			select {
+			case ch <- event.(HeartbeatEvent):
+			default:
+				atomic.AddUint64(&c.dropped.heartbeats, 1)
+				c.countDrop("heartbeats")
+			}
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
+
+// HubResponseSubscription a new, independent hub message channel, sized by MessageBufferSize and governed by OverflowPolicy.
+func (c *client) HubResponseSubscription() (<-chan MessageDataPayload, UnsubscribeFunc) {
+	ch := make(chan MessageDataPayload, c.config.MessageBufferSize)
+
+	unsubscribe := c.messageFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			c.deliverMessage(ch, event.(MessageDataPayload), done)
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
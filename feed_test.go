@@ -0,0 +1,96 @@
+package signalr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribersReceiveIndependently(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+
+	metrics := c.SubscribeToState()
+	logger := c.SubscribeToState()
+
+	//Act
+	c.setState(Connecting)
+
+	//Assert
+	for i, ch := range []<-chan ConnectionState{metrics, logger} {
+		select {
+		case state := <-ch:
+			if state != Connecting {
+				t.Errorf("subscriber %d: expected %d, received %d", i, Connecting, state)
+			}
+		default:
+			t.Errorf("subscriber %d: state change stolen by another subscriber", i)
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+
+	errs, unsubscribe := c.ErrorSubscription()
+	remaining := c.ListenToErrors()
+
+	//Act
+	unsubscribe()
+	unsubscribe() //safe to call twice
+	c.sendErr(TimeoutError("after unsubscribe"))
+
+	//Assert
+	if _, open := <-errs; open {
+		t.Errorf("expected unsubscribed channel to be closed")
+	}
+
+	select {
+	case <-remaining:
+	default:
+		t.Errorf("remaining subscriber should still receive errors")
+	}
+}
+
+func TestUnsubscribeReleasesBlockedPublish(t *testing.T) {
+	//Assemble
+	c := New(Config{OverflowPolicy: OverflowBlock}).(*client)
+
+	_, unsubscribe := c.HubResponseSubscription()
+
+	published := make(chan struct{})
+	go func() {
+		c.messageFeed.publish(MessageDataPayload{HubName: "c2"})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publish to a blocking subscriber should wait for the reader")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	//Act
+	unsubscribe()
+
+	//Assert
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe did not release the blocked publish")
+	}
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	c := New(Config{}).(*client)
+
+	finishesWithin(t, time.Second, "publish", func() {
+		c.messageFeed.publish(MessageDataPayload{})
+		c.sendErr(TimeoutError("nobody home"))
+		c.sendHeartbeat(c.newHeartbeat(HeartbeatKeepAlive, "", "nobody home"))
+	})
+
+	if dropped := c.DroppedEvents(); dropped != (DroppedEvents{}) {
+		t.Errorf("events with no subscribers are not drops, found %+v", dropped)
+	}
+}
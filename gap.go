@@ -0,0 +1,81 @@
+package signalr
+
+import (
+	"sync"
+	"time"
+)
+
+// cursorWindow the most recent message cursors, oldest first, for spotting frames the peer replays after a reconnect.
+type cursorWindow struct {
+	mutex sync.Mutex
+	size  int
+	order []string
+	seen  map[string]struct{}
+}
+
+func newCursorWindow(size int) *cursorWindow {
+	return &cursorWindow{
+		size: size,
+		seen: map[string]struct{}{},
+	}
+}
+
+// seenBefore record cursor, reporting whether it was already in the window.  always false when the window is disabled.
+func (w *cursorWindow) seenBefore(cursor string) bool {
+	if w.size <= 0 {
+		return false
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.seen[cursor]; ok {
+		return true
+	}
+
+	if len(w.order) == w.size {
+		delete(w.seen, w.order[0])
+		w.order[0] = ""
+		w.order = w.order[1:]
+	}
+
+	w.order = append(w.order, cursor)
+	w.seen[cursor] = struct{}{}
+
+	return false
+}
+
+// forget drop cursor from the window, so the frame it belongs to is accepted when the peer sends it again.
+func (w *cursorWindow) forget(cursor string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.seen[cursor]; !ok {
+		return
+	}
+
+	delete(w.seen, cursor)
+	for i, seen := range w.order {
+		if seen == cursor {
+			w.order = append(w.order[:i:i], w.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (w *cursorWindow) reset() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.order = nil
+	w.seen = map[string]struct{}{}
+}
+
+// reportGap tell consumers that messages may have been lost between lastMessageID and the fresh connection.
+func (c *client) reportGap(lastMessageID string, cause error) {
+	c.sendErr(GapDetected{
+		LastMessageID: lastMessageID,
+		Cause:         cause,
+		At:            time.Now(),
+	})
+}
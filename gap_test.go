@@ -0,0 +1,143 @@
+package signalr
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDispatchPushWithoutIdentifier(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 1}).(*client)
+	messages := c.ListenToHubResponses()
+
+	//Act
+	c.dispatchMessage(hubMessage(1))
+
+	//Assert
+	if received := drainMessages(messages); !reflect.DeepEqual(received, []string{"1"}) {
+		t.Errorf("expected push to be delivered, received %v", received)
+	}
+
+	if c.currentMessageID() != "d-1" {
+		t.Errorf("expected cursor d-1, found %q", c.currentMessageID())
+	}
+}
+
+func TestInvocationResultDoesNotMoveCursor(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	c.dispatchMessage(hubMessage(1))
+	rc := c.setResponseChan("7")
+
+	//Act
+	go c.dispatchMessage(serverMessage{Identifier: "7", Error: "nope"})
+
+	//Assert
+	select {
+	case response := <-rc:
+		if response.Error != "nope" {
+			t.Errorf("expected hub error to reach the caller, found %+v", response)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for invocation result")
+	}
+
+	if c.currentMessageID() != "d-1" {
+		t.Errorf("expected cursor to stay at d-1, found %q", c.currentMessageID())
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 10, DedupWindow: 2}).(*client)
+	messages := c.ListenToHubResponses()
+
+	//Act
+	for _, id := range []int{1, 2, 2, 1, 3, 1} {
+		c.dispatchMessage(hubMessage(id))
+	}
+
+	//Assert
+	//1 has left the window by the time it's replayed the second time.
+	if received := drainMessages(messages); !reflect.DeepEqual(received, []string{"1", "2", "3", "1"}) {
+		t.Errorf("unexpected messages after de-duplication: %v", received)
+	}
+
+	if dropped := c.DroppedEvents().Duplicates; dropped != 2 {
+		t.Errorf("expected 2 duplicates, found %d", dropped)
+	}
+}
+
+func TestDedupWindowForgottenWithConnection(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 10, DedupWindow: 10}).(*client)
+	messages := c.ListenToHubResponses()
+	c.setNegotiated(&negotiationResponse{ConnectionToken: "before-restart"})
+	c.dispatchMessage(hubMessage(1))
+	c.dispatchMessage(hubMessage(2))
+
+	//Act
+	//the fresh connection after a restart counts its cursors from the start again.
+	c.setNegotiated(&negotiationResponse{ConnectionToken: "after-restart"})
+	c.dispatchMessage(hubMessage(1))
+	c.dispatchMessage(hubMessage(2))
+
+	//Assert
+	if received := drainMessages(messages); !reflect.DeepEqual(received, []string{"1", "2", "1", "2"}) {
+		t.Errorf("expected the new connection's messages delivered, received %v", received)
+	}
+
+	if dropped := c.DroppedEvents().Duplicates; dropped != 0 {
+		t.Errorf("expected no duplicates across connections, found %d", dropped)
+	}
+}
+
+func TestDedupDisabledByDefault(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 10}).(*client)
+	messages := c.ListenToHubResponses()
+
+	//Act
+	c.dispatchMessage(hubMessage(1))
+	c.dispatchMessage(hubMessage(1))
+
+	//Assert
+	if received := drainMessages(messages); len(received) != 2 {
+		t.Errorf("expected both frames without a dedup window, received %v", received)
+	}
+}
+
+func TestGapDetectedWhenResumeFallsBack(t *testing.T) {
+	//Assemble
+	requests := make(chan *http.Request, 10)
+	server := newPeerServer(peerConfig{rejectReconnect: true, requests: requests})
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	store := &memoryCursorStore{cursor: &Cursor{ConnectionToken: "stale", ProtocolVersion: "1.5", MessageID: "d-1,2"}}
+	c := New(Config{ConnectionURL: serverURL, CursorStore: store}).(*client)
+
+	gaps := make(chan GapDetected, 1)
+	c.OnGapDetected(func(gap GapDetected) { gaps <- gap })
+
+	//Act
+	go c.Resume([]string{"c2"})
+	defer c.Reset()
+
+	//Assert
+	select {
+	case gap := <-gaps:
+		if gap.LastMessageID != "d-1,2" || gap.Cause == nil {
+			t.Errorf("unexpected gap %+v", gap)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for GapDetected")
+	}
+
+	if c.State() != Connected {
+		t.Errorf("expected a fresh connection after the gap, found %s", c.State())
+	}
+}
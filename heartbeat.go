@@ -2,6 +2,7 @@ package signalr
 
 import (
 	"fmt"
+	"time"
 )
 
 //Heartbeat interface used to inform consuming app that a signal has come in with no data, probably as a keepalive
@@ -37,3 +38,60 @@ func (hb AwkwardHeartbeat) Actual() string {
 func (hb AwkwardHeartbeat) GetError() string {
 	return string(hb)
 }
+
+// HeartbeatKind what sort of activity from the peer a HeartbeatEvent records.
+type HeartbeatKind int
+
+// Heartbeat kinds
+const (
+	//HeartbeatKeepAlive a frame with nothing to deliver, such as the peer's {} keepalive.
+	HeartbeatKeepAlive HeartbeatKind = iota
+	//HeartbeatMessage a hub message was delivered to subscribers.
+	HeartbeatMessage
+	//HeartbeatUnmatchedResponse a frame carrying an invocation ID that no CallHub is waiting on.
+	HeartbeatUnmatchedResponse
+)
+
+// String implement Stringer interface
+func (hk HeartbeatKind) String() string {
+	switch hk {
+	case HeartbeatKeepAlive:
+		return "KeepAlive"
+	case HeartbeatMessage:
+		return "Message"
+	case HeartbeatUnmatchedResponse:
+		return "UnmatchedResponse"
+	}
+
+	return fmt.Sprintf("HeartbeatKind(%d)", int(hk))
+}
+
+// HeartbeatEvent structured record of activity from the peer.  Implements Heartbeat, with Detail as the Actual text.
+type HeartbeatEvent struct {
+	Kind HeartbeatKind
+	//ReceivedAt when the frame arrived.
+	ReceivedAt time.Time
+	//SincePrevious time since the frame before it.  Zero for the first frame.
+	SincePrevious time.Duration
+	//InvocationID the identifier on the frame, for HeartbeatUnmatchedResponse.
+	InvocationID string
+	Detail       string
+}
+
+//String implement Stringer interface
+func (he HeartbeatEvent) String() string {
+	return fmt.Sprintf("%s heartbeat at %s", he.Kind, he.ReceivedAt.Format(time.RFC3339Nano))
+}
+
+func (he HeartbeatEvent) Actual() string {
+	return he.Detail
+}
+
+// Legacy the NormalHeartbeat or AwkwardHeartbeat the client sent for this event before HeartbeatEvent existed.
+func (he HeartbeatEvent) Legacy() Heartbeat {
+	if he.Kind == HeartbeatUnmatchedResponse {
+		return AwkwardHeartbeat(he.Detail)
+	}
+
+	return NormalHeartbeat(he.Detail)
+}
@@ -0,0 +1,96 @@
+package signalr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeatEventKinds(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 1, HeartbeatBufferSize: 10}).(*client)
+	c.ListenToHubResponses()
+	events, unsubscribe := c.HeartbeatEventSubscription()
+	defer unsubscribe()
+	legacy := c.ListenToHeartbeat()
+
+	//Act
+	c.dispatchMessage(serverMessage{})
+	c.dispatchMessage(hubMessage(1))
+	c.dispatchMessage(serverMessage{Identifier: "12"})
+
+	//Assert
+	expected := []struct {
+		kind         HeartbeatKind
+		invocationID string
+		legacy       Heartbeat
+	}{
+		{HeartbeatKeepAlive, "", NormalHeartbeat("Default Heartbeat.")},
+		{HeartbeatMessage, "", NormalHeartbeat("Heartbeat refreshed by subscription signal.")},
+		{HeartbeatUnmatchedResponse, "12", nil},
+	}
+
+	for _, want := range expected {
+		event := <-events
+		if event.Kind != want.kind || event.InvocationID != want.invocationID {
+			t.Errorf("expected %s heartbeat for %q, found %+v", want.kind, want.invocationID, event)
+		}
+
+		if event.ReceivedAt.IsZero() {
+			t.Errorf("expected %s heartbeat to carry a receive time", want.kind)
+		}
+
+		old := <-legacy
+		if want.legacy != nil && old != want.legacy {
+			t.Errorf("expected legacy heartbeat %#v, found %#v", want.legacy, old)
+		}
+
+		if _, awkward := old.(AwkwardHeartbeat); awkward != (want.kind == HeartbeatUnmatchedResponse) {
+			t.Errorf("unexpected legacy heartbeat type %T for %s", old, want.kind)
+		}
+	}
+}
+
+func TestHeartbeatSincePrevious(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+
+	//Act
+	c.recordMessageIn(2)
+	first := c.newHeartbeat(HeartbeatKeepAlive, "", "")
+	time.Sleep(20 * time.Millisecond)
+	c.recordMessageIn(2)
+	second := c.newHeartbeat(HeartbeatKeepAlive, "", "")
+
+	//Assert
+	if first.SincePrevious != 0 {
+		t.Errorf("expected no previous activity for the first frame, found %s", first.SincePrevious)
+	}
+
+	if second.SincePrevious < 20*time.Millisecond || second.SincePrevious > time.Second {
+		t.Errorf("unexpected time since previous frame %s", second.SincePrevious)
+	}
+
+	if !second.ReceivedAt.After(first.ReceivedAt) {
+		t.Errorf("expected second heartbeat after the first")
+	}
+}
+
+func TestOnHeartbeat(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	kinds := make(chan HeartbeatKind, 1)
+	c.OnHeartbeat(func(hb HeartbeatEvent) { kinds <- hb.Kind })
+
+	//Act
+	c.dispatchMessage(serverMessage{})
+
+	//Assert
+	select {
+	case kind := <-kinds:
+		if kind != HeartbeatKeepAlive {
+			t.Errorf("expected keepalive, found %s", kind)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for heartbeat callback")
+	}
+}
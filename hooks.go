@@ -0,0 +1,124 @@
+package signalr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultConnectedHookRetryDelay = time.Second
+
+// ConnectedHook setup work to redo on every new connection, e.g. calling a hub's subscribe method.  ctx is cancelled when
+// the connection is lost or the client is Reset.
+type ConnectedHook func(ctx context.Context, conn Connection) error
+
+type connectedHook struct {
+	id   int
+	hook ConnectedHook
+}
+
+// connectedHooks registered setup functions, kept in registration order, plus the cancel func of the connection they're
+// currently running against.
+type connectedHooks struct {
+	mutex  sync.Mutex
+	nextID int
+	hooks  []connectedHook
+	cancel context.CancelFunc
+}
+
+// OnConnected register a hook to run after the initial connect and after every reconnect.  Hooks run in registration
+// order on a goroutine of their own, so they're free to CallHub.  A failing hook is retried per ConnectedHookRetries,
+// then reported on the error feed as a ConnectedHookError.
+func (c *client) OnConnected(hook ConnectedHook) UnsubscribeFunc {
+	c.connectedHooks.mutex.Lock()
+	id := c.connectedHooks.nextID
+	c.connectedHooks.nextID++
+	c.connectedHooks.hooks = append(c.connectedHooks.hooks, connectedHook{id: id, hook: hook})
+	c.connectedHooks.mutex.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			c.connectedHooks.mutex.Lock()
+			defer c.connectedHooks.mutex.Unlock()
+
+			for i, h := range c.connectedHooks.hooks {
+				if h.id == id {
+					c.connectedHooks.hooks = append(c.connectedHooks.hooks[:i:i], c.connectedHooks.hooks[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// watchConnected start the hooks whenever the client connects, and cancel them whenever it stops being connected.  runs
+// under the state mutex, so it only ever starts goroutines.
+func (c *client) watchConnected(event interface{}, done <-chan struct{}) {
+	change := event.(StateChange)
+
+	c.connectedHooks.mutex.Lock()
+	defer c.connectedHooks.mutex.Unlock()
+
+	if c.connectedHooks.cancel != nil {
+		c.connectedHooks.cancel()
+		c.connectedHooks.cancel = nil
+	}
+
+	if change.To != Connected || len(c.connectedHooks.hooks) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.context())
+	c.connectedHooks.cancel = cancel
+
+	hooks := make([]connectedHook, len(c.connectedHooks.hooks))
+	copy(hooks, c.connectedHooks.hooks)
+
+	go c.runConnectedHooks(ctx, hooks)
+}
+
+// runConnectedHooks run each hook in turn, giving up on the lot once the connection they were started for is gone.
+func (c *client) runConnectedHooks(ctx context.Context, hooks []connectedHook) {
+	for i, h := range hooks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		attempts, err := c.runConnectedHook(ctx, h.hook)
+		if err != nil && ctx.Err() == nil {
+			c.sendErr(newConnectedHookError(
+				fmt.Sprintf("OnConnected hook %d failed after %d attempt(s)", i, attempts),
+				err,
+			))
+		}
+	}
+}
+
+// runConnectedHook call hook until it succeeds, retries run out or ctx is cancelled.  a panicking hook counts as a
+// failed attempt.
+func (c *client) runConnectedHook(ctx context.Context, hook ConnectedHook) (int, error) {
+	delay := c.config.ConnectedHookRetryDelay
+	if delay <= 0 {
+		delay = defaultConnectedHookRetryDelay
+	}
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = fmt.Errorf("hook panicked")
+		c.callbacks.invoke("OnConnected", func() { err = hook(ctx, c) })
+
+		if err == nil || attempt > c.config.ConnectedHookRetries {
+			return attempt, err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return attempt, err
+		}
+	}
+}
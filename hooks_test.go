@@ -0,0 +1,167 @@
+package signalr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOnConnectedRunsAfterEveryConnect(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	calls := make(chan string, 10)
+
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		calls <- "first"
+		return nil
+	})
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		calls <- "second"
+		return nil
+	})
+
+	//Act
+	c.setState(Connecting)
+	c.setState(Connected)
+	expectCalls(t, calls, "first", "second")
+
+	c.setState(Reconnecting)
+	c.setState(Connected)
+
+	//Assert
+	expectCalls(t, calls, "first", "second")
+}
+
+func TestOnConnectedUnsubscribe(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	calls := make(chan string, 10)
+
+	unsubscribe := c.OnConnected(func(ctx context.Context, conn Connection) error {
+		calls <- "removed"
+		return nil
+	})
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		calls <- "kept"
+		return nil
+	})
+
+	//Act
+	unsubscribe()
+	unsubscribe()
+	c.setState(Connecting)
+	c.setState(Connected)
+
+	//Assert
+	expectCalls(t, calls, "kept")
+}
+
+func TestOnConnectedRetriesThenReports(t *testing.T) {
+	//Assemble
+	c := New(Config{ConnectedHookRetries: 2, ConnectedHookRetryDelay: time.Millisecond}).(*client)
+	errs := c.ListenToErrors()
+	attempts := make(chan struct{}, 10)
+
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		attempts <- struct{}{}
+		return errors.New("subscribe rejected")
+	})
+
+	//Act
+	c.setState(Connecting)
+	c.setState(Connected)
+
+	//Assert
+	select {
+	case err := <-errs:
+		if _, ok := err.(ConnectedHookError); !ok {
+			t.Fatalf("expected ConnectedHookError, received %T: %v", err, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for hook error")
+	}
+
+	if len(attempts) != 3 {
+		t.Errorf("expected 3 attempts, found %d", len(attempts))
+	}
+}
+
+func TestOnConnectedPanicIsRecovered(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	errs := c.ListenToErrors()
+
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		panic("boom")
+	})
+
+	//Act
+	c.setState(Connecting)
+	c.setState(Connected)
+
+	//Assert
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case err := <-errs:
+			switch err.(type) {
+			case CallbackPanicError:
+				seen["panic"] = true
+			case ConnectedHookError:
+				seen["hook"] = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for errors, seen %v", seen)
+		}
+	}
+}
+
+func TestOnConnectedContextCancelledOnDisconnect(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	c.OnConnected(func(ctx context.Context, conn Connection) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	c.setState(Connecting)
+	c.setState(Connected)
+	<-started
+
+	//Act
+	c.setState(Reconnecting)
+
+	//Assert
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("hook context not cancelled when the connection dropped")
+	}
+}
+
+func expectCalls(t *testing.T, calls <-chan string, expected ...string) {
+	t.Helper()
+
+	for _, want := range expected {
+		select {
+		case got := <-calls:
+			if got != want {
+				t.Errorf("expected %s, received %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s", want)
+		}
+	}
+
+	select {
+	case extra := <-calls:
+		t.Errorf("unexpected extra call %s", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,68 @@
+package signalr
+
+import (
+	"net/url"
+)
+
+// Logger structured, leveled logging, matching the methods of *slog.Logger so one can be passed straight in.  args are
+// alternating keys and values.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// redacted stands in for secrets and, unless LogFramePayloads is set, frame contents.
+const redacted = "[REDACTED]"
+
+// sensitiveQueryKeys query parameters that grant access to a connection, never logged as is.
+var sensitiveQueryKeys = []string{accessTokenQueryKey, "connectionToken", "groupsToken"}
+
+// logging true if a Logger is configured.  Checked before building log arguments, so an unset Logger costs nothing.
+func (c *client) logging() bool {
+	return c.config.Logger != nil
+}
+
+// logURL u with its secrets redacted.
+func logURL(u url.URL) string {
+	query := u.Query()
+
+	for _, key := range sensitiveQueryKeys {
+		if query.Get(key) != "" {
+			query.Set(key, redacted)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// logFrame a frame as it should appear in the log: redacted unless LogFramePayloads is set, then passed through
+// FrameRedactor if there is one.
+func (c *client) logFrame(frame []byte) string {
+	if !c.config.LogFramePayloads {
+		return redacted
+	}
+
+	if c.config.FrameRedactor != nil {
+		frame = c.config.FrameRedactor(frame)
+	}
+
+	return string(frame)
+}
+
+// logFrameIn trace a frame received from the peer.
+func (c *client) logFrameIn(frame []byte) {
+	if c.logging() {
+		c.config.Logger.Debug("signalr frame received", "bytes", len(frame), "frame", c.logFrame(frame))
+	}
+}
+
+// logFrameOut trace a frame sent to the peer.
+func (c *client) logFrameOut(frame []byte) {
+	if c.logging() {
+		c.config.Logger.Debug("signalr frame sent", "bytes", len(frame), "frame", c.logFrame(frame))
+	}
+}
@@ -0,0 +1,165 @@
+package signalr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// *slog.Logger must satisfy Logger without an adapter.
+var _ Logger = slog.New(slog.NewTextHandler(ioutil.Discard, nil))
+
+type logEntry struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+type recordingLogger struct {
+	mutex   sync.Mutex
+	entries []logEntry
+}
+
+func (rl *recordingLogger) record(level string, msg string, args []interface{}) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.entries = append(rl.entries, logEntry{level, msg, args})
+}
+
+func (rl *recordingLogger) Debug(msg string, args ...interface{}) { rl.record("debug", msg, args) }
+func (rl *recordingLogger) Info(msg string, args ...interface{})  { rl.record("info", msg, args) }
+func (rl *recordingLogger) Warn(msg string, args ...interface{})  { rl.record("warn", msg, args) }
+func (rl *recordingLogger) Error(msg string, args ...interface{}) { rl.record("error", msg, args) }
+
+// find the first entry with msg, and its args as a map.
+func (rl *recordingLogger) find(msg string) (map[string]interface{}, bool) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for _, entry := range rl.entries {
+		if entry.msg == msg {
+			args := map[string]interface{}{}
+			for i := 0; i+1 < len(entry.args); i += 2 {
+				args[fmt.Sprintf("%v", entry.args[i])] = entry.args[i+1]
+			}
+			return args, true
+		}
+	}
+
+	return nil, false
+}
+
+func TestLogFrameRedaction(t *testing.T) {
+	frame := []byte(`{"M":[{"H":"c2","A":["secret"]}]}`)
+
+	cases := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"redacted by default", Config{}, redacted},
+		{"payloads", Config{LogFramePayloads: true}, string(frame)},
+		{"custom redactor", Config{
+			LogFramePayloads: true,
+			FrameRedactor: func(frame []byte) []byte {
+				return bytes.Replace(frame, []byte("secret"), []byte("***"), -1)
+			},
+		}, `{"M":[{"H":"c2","A":["***"]}]}`},
+	}
+
+	for _, tc := range cases {
+		logger := &recordingLogger{}
+		tc.config.Logger = logger
+		c := New(tc.config).(*client)
+
+		c.logFrameIn(frame)
+
+		args, ok := logger.find("signalr frame received")
+		if !ok {
+			t.Fatalf("%s: frame not logged", tc.name)
+		}
+
+		if args["frame"] != tc.expected || args["bytes"] != len(frame) {
+			t.Errorf("%s: expected frame %q, logged %+v", tc.name, tc.expected, args)
+		}
+	}
+}
+
+func TestLogURLRedactsSecrets(t *testing.T) {
+	u, _ := url.Parse("wss://peer.example/signalr/connect?access_token=t0k3n&connectionToken=abc&transport=webSockets")
+
+	logged := logURL(*u)
+
+	if strings.Contains(logged, "t0k3n") || strings.Contains(logged, "abc") {
+		t.Errorf("secrets leaked into log url %s", logged)
+	}
+
+	if !strings.Contains(logged, "transport=webSockets") {
+		t.Errorf("expected harmless parameters to survive, found %s", logged)
+	}
+}
+
+func TestLogsNegotiateAndStateChanges(t *testing.T) {
+	//Assemble
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ConnectionToken":"abc","ProtocolVersion":"1.5","KeepAliveTimeout":20}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	logger := &recordingLogger{}
+	c := New(Config{
+		ConnectionURL:        serverURL,
+		Logger:               logger,
+		AccessTokenPlacement: AccessTokenQuery,
+		AccessTokenProvider: func(ctx context.Context) (string, error) {
+			return "t0k3n", nil
+		},
+	}).(*client)
+
+	//Act
+	c.setState(Connecting)
+	if _, err := c.negotiate(); err != nil {
+		t.Fatalf("unexpected negotiate error: %v", err)
+	}
+
+	//Assert
+	args, ok := logger.find("signalr negotiate")
+	if !ok {
+		t.Fatal("negotiate request not logged")
+	}
+
+	if logged := fmt.Sprintf("%v", args["url"]); strings.Contains(logged, "t0k3n") {
+		t.Errorf("access token leaked into log: %s", logged)
+	}
+
+	if args, ok = logger.find("signalr negotiate response"); !ok || args["status"] != http.StatusOK {
+		t.Errorf("negotiate response not logged: %+v", args)
+	}
+
+	if args, ok = logger.find("signalr state change"); !ok || args["from"] != "Ready" || args["to"] != "Connecting" {
+		t.Errorf("state change not logged: %+v", args)
+	}
+}
+
+func TestNoLoggerNoLogging(t *testing.T) {
+	c := New(Config{}).(*client)
+
+	if c.logging() {
+		t.Error("expected logging to be off without a Logger")
+	}
+
+	//must not panic without a logger.
+	c.logFrameIn([]byte("{}"))
+	c.logFrameOut([]byte("{}"))
+	c.setState(Connecting)
+}
@@ -0,0 +1,129 @@
+package signalr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Metrics sink for connection and invocation telemetry.  Every metric name is one of the Metric constants and always
+// carries the same label keys, so adapters can register collectors lazily on first use.  Calls come from the read loop
+// and CallHub callers, so implementations must be safe for concurrent use and quick.
+type Metrics interface {
+	//IncCounter add value to a monotonically increasing counter.
+	IncCounter(name string, labels map[string]string, value float64)
+	//SetGauge set a gauge to value.
+	SetGauge(name string, labels map[string]string, value float64)
+	//ObserveHistogram record one observation, in seconds for durations.
+	ObserveHistogram(name string, labels map[string]string, value float64)
+}
+
+// Metric names and their labels
+const (
+	//MetricConnects counter of sockets established.  labels: kind (connect, reconnect).
+	MetricConnects = "signalr_connects_total"
+	//MetricReconnectAttempts counter of reconnect dial attempts.  no labels.
+	MetricReconnectAttempts = "signalr_reconnect_attempts_total"
+	//MetricStateDuration histogram of seconds spent in a state, observed on leaving it.  labels: state.
+	MetricStateDuration = "signalr_state_duration_seconds"
+	//MetricFrames counter of websocket frames.  labels: direction (in, out).
+	MetricFrames = "signalr_frames_total"
+	//MetricBytes counter of frame payload bytes.  labels: direction (in, out).
+	MetricBytes = "signalr_bytes_total"
+	//MetricInvocationDuration histogram of CallHub round trip seconds.  labels: hub, method.
+	MetricInvocationDuration = "signalr_invocation_duration_seconds"
+	//MetricInvocationFailures counter of failed CallHub invocations.  labels: hub, method, error (the error's type name).
+	MetricInvocationFailures = "signalr_invocation_failures_total"
+	//MetricPendingInvocations gauge of CallHub invocations awaiting a response.  no labels.
+	MetricPendingInvocations = "signalr_pending_invocations"
+	//MetricDropped counter of discarded events.  labels: kind (messages, errors, heartbeats, states, duplicates, callbacks).
+	MetricDropped = "signalr_dropped_total"
+)
+
+// measuring true if a Metrics sink is configured.  Checked before building labels, so an unset sink costs nothing.
+func (c *client) measuring() bool {
+	return c.config.Metrics != nil
+}
+
+// errorType label value for err: its type name, without package or pointer.
+func errorType(err error) string {
+	name := fmt.Sprintf("%T", err)
+	name = strings.TrimPrefix(name, "*")
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// countFrame record a frame crossing the socket in direction "in" or "out".
+func (c *client) countFrame(direction string, size int) {
+	if !c.measuring() {
+		return
+	}
+
+	labels := map[string]string{"direction": direction}
+	c.config.Metrics.IncCounter(MetricFrames, labels, 1)
+	c.config.Metrics.IncCounter(MetricBytes, labels, float64(size))
+}
+
+// countDrop record a discarded event of the given kind.
+func (c *client) countDrop(kind string) {
+	if c.measuring() {
+		c.config.Metrics.IncCounter(MetricDropped, map[string]string{"kind": kind}, 1)
+	}
+}
+
+// countReconnectAttempt record a reconnect dial.
+func (c *client) countReconnectAttempt() {
+	if c.measuring() {
+		c.config.Metrics.IncCounter(MetricReconnectAttempts, nil, 1)
+	}
+}
+
+// measureStateChange record how long the previous state lasted, and any new socket.
+func (c *client) measureStateChange(change StateChange, since time.Time) {
+	if !c.measuring() {
+		return
+	}
+
+	c.config.Metrics.ObserveHistogram(
+		MetricStateDuration,
+		map[string]string{"state": change.From.String()},
+		change.At.Sub(since).Seconds(),
+	)
+
+	if change.To == Connected {
+		kind := "connect"
+		if change.From == Reconnecting {
+			kind = "reconnect"
+		}
+		c.config.Metrics.IncCounter(MetricConnects, map[string]string{"kind": kind}, 1)
+	}
+}
+
+// measureInvocation record a CallHub round trip, and its failure if err is set.
+func (c *client) measureInvocation(payload CallHubPayload, elapsed time.Duration, err error) {
+	if !c.measuring() {
+		return
+	}
+
+	labels := map[string]string{"hub": payload.Hub, "method": payload.Method}
+	c.config.Metrics.ObserveHistogram(MetricInvocationDuration, labels, elapsed.Seconds())
+
+	if err != nil {
+		c.config.Metrics.IncCounter(
+			MetricInvocationFailures,
+			map[string]string{"hub": payload.Hub, "method": payload.Method, "error": errorType(err)},
+			1,
+		)
+	}
+}
+
+// gaugePendingInvocations record the number of open response channels.  called with responseChannelMutex held.
+func (c *client) gaugePendingInvocations() {
+	if c.measuring() {
+		c.config.Metrics.SetGauge(MetricPendingInvocations, nil, float64(len(c.responseChannels)))
+	}
+}
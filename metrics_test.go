@@ -0,0 +1,158 @@
+package signalr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	mutex      sync.Mutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string][]float64
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		counters:   map[string]float64{},
+		gauges:     map[string]float64{},
+		histograms: map[string][]float64{},
+	}
+}
+
+// metricKey name{k=v,...} with sorted labels.
+func metricKey(name string, labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+func (rm *recordingMetrics) IncCounter(name string, labels map[string]string, value float64) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.counters[metricKey(name, labels)] += value
+}
+
+func (rm *recordingMetrics) SetGauge(name string, labels map[string]string, value float64) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.gauges[metricKey(name, labels)] = value
+}
+
+func (rm *recordingMetrics) ObserveHistogram(name string, labels map[string]string, value float64) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	key := metricKey(name, labels)
+	rm.histograms[key] = append(rm.histograms[key], value)
+}
+
+func (rm *recordingMetrics) counter(key string) float64 {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	return rm.counters[key]
+}
+
+func TestMetricsStateChanges(t *testing.T) {
+	//Assemble
+	metrics := newRecordingMetrics()
+	c := New(Config{Metrics: metrics}).(*client)
+
+	//Act
+	c.setState(Connecting)
+	time.Sleep(10 * time.Millisecond)
+	c.setState(Connected)
+	c.setState(Reconnecting)
+	c.setState(Connected)
+
+	//Assert
+	if got := metrics.counter("signalr_connects_total{kind=connect}"); got != 1 {
+		t.Errorf("expected 1 connect, found %v", got)
+	}
+
+	if got := metrics.counter("signalr_connects_total{kind=reconnect}"); got != 1 {
+		t.Errorf("expected 1 reconnect, found %v", got)
+	}
+
+	connecting := metrics.histograms["signalr_state_duration_seconds{state=Connecting}"]
+	if len(connecting) != 1 || connecting[0] < 0.01 {
+		t.Errorf("expected one Connecting duration of at least 10ms, found %v", connecting)
+	}
+}
+
+func TestMetricsFramesAndDrops(t *testing.T) {
+	//Assemble
+	metrics := newRecordingMetrics()
+	c := New(Config{Metrics: metrics, DedupWindow: 1}).(*client)
+
+	//Act
+	c.recordMessageIn(12)
+	c.dispatchMessage(serverMessage{Cursor: "d-1"})
+	c.dispatchMessage(serverMessage{Cursor: "d-1"})
+	c.ListenToErrors()
+	for i := 0; i <= c.config.ErrorBufferSize; i++ {
+		c.sendErr(errors.New("overflow"))
+	}
+
+	//Assert
+	expected := map[string]float64{
+		"signalr_frames_total{direction=in}":     1,
+		"signalr_bytes_total{direction=in}":      12,
+		"signalr_dropped_total{kind=duplicates}": 1,
+		"signalr_dropped_total{kind=errors}":     1,
+	}
+
+	for key, want := range expected {
+		if got := metrics.counter(key); got != want {
+			t.Errorf("expected %s = %v, found %v", key, want, got)
+		}
+	}
+}
+
+func TestMetricsInvocationFailure(t *testing.T) {
+	//Assemble
+	metrics := newRecordingMetrics()
+	c := New(Config{Metrics: metrics}).(*client)
+
+	//Act
+	err := c.CallHub(CallHubPayload{Hub: "c2", Method: "QueryExchangeState"}, nil)
+
+	//Assert
+	if err == nil {
+		t.Fatal("expected CallHub without a socket to fail")
+	}
+
+	if got := metrics.counter("signalr_invocation_failures_total{error=SocketError,hub=c2,method=QueryExchangeState}"); got != 1 {
+		t.Errorf("expected one SocketError failure, found %v in %v", got, metrics.counters)
+	}
+
+	if got := metrics.histograms["signalr_invocation_duration_seconds{hub=c2,method=QueryExchangeState}"]; len(got) != 1 {
+		t.Errorf("expected one invocation duration, found %v", got)
+	}
+
+	if got, ok := metrics.gauges["signalr_pending_invocations{}"]; !ok || got != 0 {
+		t.Errorf("expected the unsent invocation to be cleared from pending, found %v", got)
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	cases := map[string]error{
+		"CallHubError":         NewCallHubError("x", nil),
+		"BrokenWebSocketError": NewBrokenWebSocketError("x", nil),
+		"errorString":          errors.New("x"),
+	}
+
+	for want, err := range cases {
+		if got := errorType(err); got != want {
+			t.Errorf("expected %s, found %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package signalr
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// OverflowPolicy what happens when the hub response channel is full and another message arrives.
+type OverflowPolicy int
+
+// Overflow policy values
+const (
+	//OverflowBlock wait for the consumer.  The default, and the historical behavior, but a slow consumer stalls the read loop.
+	OverflowBlock OverflowPolicy = iota
+	//OverflowDropOldest discard the oldest buffered message to make room.
+	OverflowDropOldest
+	//OverflowDropNewest discard the incoming message.
+	OverflowDropNewest
+	//OverflowDisconnect close the socket and let the normal reconnect logic resume from the last message.
+	OverflowDisconnect
+)
+
+// String implement Stringer interface
+func (op OverflowPolicy) String() string {
+	switch op {
+	case OverflowBlock:
+		return "Block"
+	case OverflowDropOldest:
+		return "DropOldest"
+	case OverflowDropNewest:
+		return "DropNewest"
+	case OverflowDisconnect:
+		return "Disconnect"
+	}
+
+	return fmt.Sprintf("OverflowPolicy(%d)", int(op))
+}
+
+// DroppedEvents totals of deliveries discarded because the consumer's channel was full.
+type DroppedEvents struct {
+	Messages   uint64
+	Errors     uint64
+	Heartbeats uint64
+	States     uint64
+	//Duplicates hub frames discarded because their cursor was already seen within DedupWindow.
+	Duplicates uint64
+	//Callbacks handler calls discarded because CallbackBufferSize of them were already waiting.
+	Callbacks uint64
+}
+
+// dropCounters running totals of discarded deliveries, updated atomically.
+type dropCounters struct {
+	messages   uint64
+	errors     uint64
+	heartbeats uint64
+	states     uint64
+	duplicates uint64
+	callbacks  uint64
+
+	//set when OverflowDisconnect dropped part of the frame being dispatched, which then must not move the cursor.
+	disconnected uint32
+}
+
+// DroppedEvents snapshot of everything discarded since the client was created.
+func (c *client) DroppedEvents() DroppedEvents {
+	return DroppedEvents{
+		Messages:   atomic.LoadUint64(&c.dropped.messages),
+		Errors:     atomic.LoadUint64(&c.dropped.errors),
+		Heartbeats: atomic.LoadUint64(&c.dropped.heartbeats),
+		States:     atomic.LoadUint64(&c.dropped.states),
+		Duplicates: atomic.LoadUint64(&c.dropped.duplicates),
+		Callbacks:  atomic.LoadUint64(&c.dropped.callbacks),
+	}
+}
+
+// deliverMessage hand a payload to one subscriber according to the configured overflow policy.
+func (c *client) deliverMessage(ch chan MessageDataPayload, payload MessageDataPayload, done <-chan struct{}) {
+	if c.config.OverflowPolicy == OverflowBlock {
+		//Reset may come from the very goroutine that isn't reading.
+		select {
+		case ch <- payload:
+		case <-done:
+		case <-c.context().Done():
+		}
+		return
+	}
+
+	select {
+	case ch <- payload:
+		return
+	default:
+	}
+
+	switch c.config.OverflowPolicy {
+	case OverflowDropOldest:
+		// an unbuffered channel has nothing to evict, so the incoming message goes instead.
+		for cap(ch) > 0 {
+			select {
+			case <-ch:
+				c.reportDroppedMessage()
+			default:
+			}
+
+			select {
+			case ch <- payload:
+				return
+			default:
+			}
+		}
+		c.reportDroppedMessage()
+	case OverflowDisconnect:
+		atomic.StoreUint32(&c.dropped.disconnected, 1)
+		c.reportDroppedMessage()
+		if socket := c.currentSocket(); socket != nil {
+			socket.Close()
+		}
+	default:
+		c.reportDroppedMessage()
+	}
+}
+
+// reportDroppedMessage count a discarded message and let the consumer know.
+func (c *client) reportDroppedMessage() {
+	c.countDrop("messages")
+	c.sendErr(MessageDroppedError{
+		Policy:  c.config.OverflowPolicy,
+		Dropped: atomic.AddUint64(&c.dropped.messages, 1),
+	})
+}
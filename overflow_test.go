@@ -0,0 +1,158 @@
+package signalr
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+func drainMessages(messages <-chan MessageDataPayload) []string {
+	var received []string
+
+	for {
+		select {
+		case msg := <-messages:
+			received = append(received, string(msg.Arguments[0]))
+		default:
+			return received
+		}
+	}
+}
+
+func TestOverflowDropPolicies(t *testing.T) {
+	cases := []struct {
+		policy   OverflowPolicy
+		expected []string
+	}{
+		{OverflowDropOldest, []string{"4", "5"}},
+		{OverflowDropNewest, []string{"1", "2"}},
+	}
+
+	for _, tc := range cases {
+		//Assemble
+		c := New(Config{MessageBufferSize: 2, OverflowPolicy: tc.policy}).(*client)
+		messages := c.ListenToHubResponses()
+		errs := c.ListenToErrors()
+
+		//Act
+		for i := 1; i <= 5; i++ {
+			c.dispatchMessage(hubMessage(i))
+		}
+
+		//Assert
+		received := drainMessages(messages)
+		if fmt.Sprint(received) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s: expected %v, received %v", tc.policy, tc.expected, received)
+		}
+
+		var last MessageDroppedError
+		for len(errs) > 0 {
+			last = (<-errs).(MessageDroppedError)
+		}
+
+		if last.Dropped != 3 || last.Policy != tc.policy {
+			t.Errorf("%s: expected 3 drops reported, found %+v", tc.policy, last)
+		}
+	}
+}
+
+func TestOverflowDropOldestUnbuffered(t *testing.T) {
+	c := New(Config{OverflowPolicy: OverflowDropOldest}).(*client)
+	c.ListenToHubResponses()
+
+	done := make(chan struct{})
+	go func() {
+		c.dispatchMessage(hubMessage(1))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drop oldest on an unbuffered channel should not spin forever")
+	}
+}
+
+func TestOverflowDisconnect(t *testing.T) {
+	//Assemble
+	var frames []string
+	for i := 1; i <= 3; i++ {
+		data, _ := json.Marshal(hubMessage(i))
+		frames = append(frames, string(data))
+	}
+
+	c, closeServer := dialPeerServer(t, Config{
+		MessageBufferSize: 1,
+		OverflowPolicy:    OverflowDisconnect,
+	}, peerConfig{frames: frames})
+	defer closeServer()
+	c.ListenToHubResponses()
+
+	//Act
+	done := make(chan struct{})
+	go func() {
+		c.listenToWebSocketData(5 * time.Second)
+		close(done)
+	}()
+
+	//Assert
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected overflow to close the socket and end the read loop")
+	}
+
+	if c.State() != Disconnected {
+		t.Errorf("expected disconnected state, found %d", c.State())
+	}
+}
+
+func TestOverflowDisconnectResumesAtDroppedMessage(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	c := New(Config{
+		ConnectionURL:     server.URL(),
+		MessageBufferSize: 1,
+		OverflowPolicy:    OverflowDisconnect,
+	}).(*client)
+	messages := c.ListenToHubResponses()
+	states := c.SubscribeToState()
+
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	waitForState(t, states, Connected)
+
+	//Act
+	server.Push("c2", "uE", 1)
+	server.Push("c2", "uE", 2)
+	waitForState(t, states, Reconnecting)
+	query := c.reconnectQuery(&negotiationResponse{}, nil)
+	first := <-messages
+
+	//Assert
+	if messageID := query.Get("messageId"); messageID != "d-1" {
+		t.Errorf("expected the reconnect to resume after the last delivered message d-1, found %q", messageID)
+	}
+
+	if string(first.Arguments[0]) != "1" {
+		t.Errorf("unexpected first push %+v", first)
+	}
+
+	select {
+	case second := <-messages:
+		if string(second.Arguments[0]) != "2" {
+			t.Errorf("expected the dropped push redelivered, found %+v", second)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the dropped push after reconnecting")
+	}
+
+	if dropped := c.DroppedEvents(); dropped.Messages != 1 || dropped.Duplicates != 0 {
+		t.Errorf("expected one drop and no duplicates, found %+v", dropped)
+	}
+}
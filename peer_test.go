@@ -0,0 +1,92 @@
+package signalr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// peerConfig shapes the server from newPeerServer.  The zero value negotiates, then accepts websockets and holds
+// them open without ever sending anything.
+type peerConfig struct {
+	//frames are written to each socket as soon as it's upgraded.
+	frames []string
+	//rejectReconnect answers reconnects with a 404, as a peer that has forgotten the connection does.
+	rejectReconnect bool
+	//requests receives every request the server sees, when set.
+	requests chan<- *http.Request
+}
+
+// newPeerServer a bare classic SignalR peer, for tests that need the wire more tightly scripted than signalrtest
+// allows.
+func newPeerServer(peer peerConfig) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer.requests != nil {
+			peer.requests <- r
+		}
+
+		if strings.HasSuffix(r.URL.Path, negotiatePath) {
+			w.Write([]byte(`{"ConnectionToken":"fresh","ProtocolVersion":"1.5","KeepAliveTimeout":20}`))
+			return
+		}
+
+		if peer.rejectReconnect && strings.HasSuffix(r.URL.Path, reconnectPath) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, frame := range peer.frames {
+			conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+
+		//hold the socket open; only the client closing it should end the read loop.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+// dialPeerServer a client with its websocket dialed to a newPeerServer, skipping negotiation.
+func dialPeerServer(t *testing.T, cfg Config, peer peerConfig) (*client, func()) {
+	server := newPeerServer(peer)
+	serverURL, _ := url.Parse(server.URL)
+
+	cfg.ConnectionURL = serverURL
+	c := New(cfg).(*client)
+	c.setState(Connecting)
+
+	if err := c.dialWebSocket(c.config.ConnectPath, url.Values{}, time.Second, time.Time{}); err != nil {
+		server.Close()
+		t.Fatalf("unable to dial test server: %v", err)
+	}
+
+	return c, server.Close
+}
+
+// hubMessage a hub push frame as the peer sends it: a cursor and data, but no invocation identifier.
+func hubMessage(id int) serverMessage {
+	data, _ := json.Marshal(MessageDataPayload{
+		HubName:   "c2",
+		Method:    "uE",
+		Arguments: []json.RawMessage{json.RawMessage(fmt.Sprintf("%d", id))},
+	})
+
+	return serverMessage{Cursor: fmt.Sprintf("d-%d", id), Data: []json.RawMessage{data}}
+}
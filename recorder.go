@@ -0,0 +1,126 @@
+package signalr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// FrameDirection which way a frame crossed the socket.
+type FrameDirection string
+
+// Frame directions
+const (
+	FrameIn  FrameDirection = "in"
+	FrameOut FrameDirection = "out"
+)
+
+// Frame a raw websocket frame as it crossed the socket.  Data is kept as a string rather than parsed, so frames the
+// client couldn't parse are recorded, and replayed, exactly as they arrived.
+type Frame struct {
+	At        time.Time      `json:"at"`
+	Direction FrameDirection `json:"direction"`
+	Data      string         `json:"data"`
+}
+
+// FrameRecorder receives every text frame sent and received, in order, from the socket read loop and hub message writes.
+// Record is called inline, so it must be quick and safe for concurrent use.
+type FrameRecorder interface {
+	Record(frame Frame)
+}
+
+// NDJSONRecorder FrameRecorder writing one JSON Frame per line, the format Replay reads back.
+type NDJSONRecorder struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewNDJSONRecorder record frames to w.
+func NewNDJSONRecorder(w io.Writer) *NDJSONRecorder {
+	return &NDJSONRecorder{encoder: json.NewEncoder(w)}
+}
+
+// Record implement FrameRecorder.  After the first write error, frames are discarded; see Err.
+func (r *NDJSONRecorder) Record(frame Frame) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err == nil {
+		r.err = r.encoder.Encode(frame)
+	}
+}
+
+// Err the write error that stopped recording, if any.
+func (r *NDJSONRecorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.err
+}
+
+// recordFrame hand a frame to the configured FrameRecorder, if any.
+func (c *client) recordFrame(direction FrameDirection, data []byte) {
+	if c.config.FrameRecorder != nil {
+		c.config.FrameRecorder.Record(Frame{At: time.Now(), Direction: direction, Data: string(data)})
+	}
+}
+
+// Replay dispatch the received frames of an NDJSONRecorder recording through the client as if they had just been read
+// from the socket, so hub message, error and heartbeat feeds and their callbacks see them as they did live.  Sent frames
+// are skipped.  speed scales the original gaps between frames: 1 replays in real time, 10 ten times faster, and 0 or
+// less as fast as possible.  Frames that don't parse are reported as SocketErrors and replay carries on; a malformed
+// recording line stops it.
+//
+// Replay is for an idle client: it fails with a ReplayError while Connect is running.  Replayed frames aren't traffic,
+// so they stay out of Stats, metrics, frame logging and OnReceived, and their cursors, groups tokens and invocation
+// identifiers are ignored: replay never moves the cursor, saves to the CursorStore, trips DedupWindow or answers a
+// CallHub.
+func (c *client) Replay(ctx context.Context, r io.Reader, speed float64) error {
+	var (
+		decoder  = json.NewDecoder(r)
+		previous time.Time
+	)
+
+	for line := 1; ; line++ {
+		if c.connecting() {
+			return newReplayError(fmt.Sprintf("Unable to replay frame %d", line), errors.New("Connect is running"))
+		}
+
+		var frame Frame
+		if err := decoder.Decode(&frame); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return newReplayError(fmt.Sprintf("Unable to read recorded frame %d", line), err)
+		}
+
+		if frame.Direction != FrameIn {
+			continue
+		}
+
+		if speed > 0 && !previous.IsZero() && frame.At.After(previous) {
+			select {
+			case <-time.After(time.Duration(float64(frame.At.Sub(previous)) / speed)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		previous = frame.At
+
+		var message serverMessage
+		if err := json.Unmarshal([]byte(frame.Data), &message); err != nil {
+			c.sendErr(NewSocketError(fmt.Sprintf("Unable to parse replayed frame %d", line), err))
+			continue
+		}
+
+		//the recording's position and invocations mean nothing to the live client.
+		message.Cursor, message.GroupsToken, message.Identifier = "", "", ""
+		c.dispatchMessage(message)
+	}
+}
@@ -0,0 +1,252 @@
+package signalr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+// recording NDJSON of frames received gap apart, with a sent frame between each.
+func recording(t *testing.T, gap time.Duration, frames ...string) *bytes.Buffer {
+	var (
+		buf      bytes.Buffer
+		recorder = NewNDJSONRecorder(&buf)
+		at       = time.Now()
+	)
+
+	for _, data := range frames {
+		recorder.Record(Frame{At: at, Direction: FrameIn, Data: data})
+		recorder.Record(Frame{At: at, Direction: FrameOut, Data: `{"H":"c2"}`})
+		at = at.Add(gap)
+	}
+
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("unexpected recording error: %v", err)
+	}
+
+	return &buf
+}
+
+func marshalMessage(t *testing.T, msg serverMessage) string {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("unable to marshal message: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestRecordSentFrames(t *testing.T) {
+	//Assemble
+	var buf bytes.Buffer
+	c, closeServer := dialPeerServer(t, Config{FrameRecorder: NewNDJSONRecorder(&buf)}, peerConfig{})
+	defer closeServer()
+
+	//Act
+	if err := c.sendHubMessage([]byte(`{"H":"c2","M":"QueryExchangeState"}`)); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	//Assert
+	var frame Frame
+	if err := json.Unmarshal(buf.Bytes(), &frame); err != nil {
+		t.Fatalf("expected one NDJSON frame, found %q: %v", buf.String(), err)
+	}
+
+	if frame.Direction != FrameOut || frame.Data != `{"H":"c2","M":"QueryExchangeState"}` || frame.At.IsZero() {
+		t.Errorf("unexpected recorded frame %+v", frame)
+	}
+}
+
+func TestReplayDispatchesReceivedFrames(t *testing.T) {
+	//Assemble
+	c := New(Config{MessageBufferSize: 2}).(*client)
+	messages := c.ListenToHubResponses()
+	buf := recording(t, time.Millisecond, marshalMessage(t, hubMessage(1)), marshalMessage(t, hubMessage(2)))
+
+	//Act
+	err := c.Replay(context.Background(), buf, 0)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	for _, want := range []string{"d-1", "d-2"} {
+		select {
+		case <-messages:
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for replayed message %s", want)
+		}
+	}
+
+	if got := c.Stats().MessagesIn; got != 0 {
+		t.Errorf("expected replayed frames to stay out of Stats, found %d messages in", got)
+	}
+
+	if got := c.currentMessageID(); got != "" {
+		t.Errorf("expected the replayed cursors to be ignored, found %s", got)
+	}
+}
+
+func TestReplayLeavesCursorAlone(t *testing.T) {
+	//Assemble
+	store := &memoryCursorStore{}
+	c := New(Config{
+		MessageBufferSize:  2,
+		DedupWindow:        10,
+		CursorStore:        store,
+		CursorSaveInterval: time.Nanosecond,
+	}).(*client)
+	c.setNegotiated(&negotiationResponse{ConnectionToken: "live"})
+	c.updateMessageID("d-7")
+	saves := store.saves
+	messages := c.ListenToHubResponses()
+
+	replayed := hubMessage(1)
+	replayed.GroupsToken = "recorded"
+	buf := recording(t, time.Millisecond, marshalMessage(t, replayed), marshalMessage(t, replayed))
+
+	//Act
+	err := c.Replay(context.Background(), buf, 0)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Errorf("expected both replayed frames, dedup window notwithstanding, found %d", len(messages))
+	}
+
+	if cursor, _ := c.cursor(); cursor.MessageID != "d-7" || cursor.GroupsToken != "" {
+		t.Errorf("expected the live cursor untouched, found %+v", cursor)
+	}
+
+	if store.saves != saves || store.load().MessageID != "d-7" {
+		t.Errorf("expected nothing saved during replay, found %d saves of %+v", store.saves-saves, store.load())
+	}
+}
+
+func TestReplayLeavesLiveClientAlone(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	received := make(chan json.RawMessage, 1)
+	c.OnReceived(func(raw json.RawMessage) { received <- raw })
+	pending := c.setResponseChan("1")
+	defer c.delResponseChan("1")
+
+	response := hubMessage(1)
+	response.Identifier = "1"
+	response.Data = nil
+	response.Result = json.RawMessage(`"recorded"`)
+
+	//Act
+	err := c.Replay(context.Background(), recording(t, 0, marshalMessage(t, response)), 0)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	select {
+	case msg := <-pending:
+		t.Errorf("expected the recorded result kept from the pending CallHub, found %+v", msg)
+	case raw := <-received:
+		t.Errorf("expected the replayed frame kept from OnReceived, found %s", raw)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplayRefusedWhileConnected(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	c := New(Config{ConnectionURL: server.URL()}).(*client)
+	messages := c.ListenToHubResponses()
+	states := c.SubscribeToState()
+
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	waitForState(t, states, Connected)
+
+	//Act
+	err := c.Replay(context.Background(), recording(t, 0, marshalMessage(t, hubMessage(1))), 0)
+
+	//Assert
+	if _, ok := err.(ReplayError); !ok {
+		t.Errorf("expected a ReplayError while connected, found %T: %v", err, err)
+	}
+
+	select {
+	case msg := <-messages:
+		t.Errorf("expected nothing replayed, found %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	buf := recording(t, 200*time.Millisecond, "{}", "{}", "{}")
+	start := time.Now()
+
+	//Act
+	err := c.Replay(context.Background(), buf, 4)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("expected 400ms of recording to replay in about 100ms at 4x, took %s", elapsed)
+	}
+}
+
+func TestReplayCancelled(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	buf := recording(t, time.Hour, "{}", "{}")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	//Act
+	err := c.Replay(ctx, buf, 1)
+
+	//Assert
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the replay to stop with its context, found %v", err)
+	}
+}
+
+func TestReplayBadFrames(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	errs := c.ListenToErrors()
+	buf := recording(t, 0, "not json")
+	buf.WriteString("not a frame\n")
+
+	//Act
+	err := c.Replay(context.Background(), buf, 0)
+
+	//Assert
+	if _, ok := err.(ReplayError); !ok || !strings.Contains(err.Error(), "frame 3") {
+		t.Errorf("expected a ReplayError for the malformed line, found %T: %v", err, err)
+	}
+
+	select {
+	case err := <-errs:
+		if _, ok := err.(SocketError); !ok {
+			t.Errorf("expected a SocketError for the unparseable frame, found %T: %v", err, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout waiting for the unparseable frame error")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,14 +18,33 @@ type CallHubPayload struct {
 	Identifier string        `json:"I,omitempty"`
 }
 
-var (
-  pingMessage = CallHubPayload{}
-)
+// SendPing Sends a websocket ping to the signalr peer, returning the round trip time once its pong arrives.  Successful
+// pings count towards the latency in Stats.
+func (c *client) SendPing() (time.Duration, error) {
+	payload := pingPayload()
+	pong := c.latency.await(string(payload))
+	defer c.latency.forget(string(payload))
 
-// SendPing Sends a ping message to the signalr hub.
-func (c client) SendPing() error {
-  var result string
-  return c.CallHub(pingMessage, &result)
+	if socket := c.currentSocket(); socket == nil {
+		err := NewSocketError("Unable to send ping to socket hub", errors.New("websocket not connected"))
+		c.sendErr(err)
+		return 0, err
+	} else if err := socket.WriteControl(websocket.PingMessage, payload, time.Now().Add(pingWriteTimeout)); err != nil {
+		err = NewSocketError("Unable to send ping to socket hub", err)
+		c.sendErr(err)
+		return 0, err
+	}
+
+	select {
+	case rtt := <-pong:
+		return rtt, nil
+	case <-time.After(pongTimeout):
+		err := TimeoutError(fmt.Sprintf("No pong from the signalr peer within %s", pongTimeout))
+		c.sendErr(err)
+		return 0, err
+	case <-c.context().Done():
+		return 0, NewSocketError("Ping abandoned by Reset", c.context().Err())
+	}
 }
 
 func (c *client) getNextIdentifier() int {
@@ -36,10 +57,26 @@ func (c *client) getNextIdentifier() int {
 }
 
 // CallHub send a message to the signalr peer.  Sets unique identifier in threadsafe way.
-// Result of the callhub is set into resultPayload
+// Result of the callhub is set into resultPayload, or discarded if resultPayload is nil.
 func (c *client) CallHub(payload CallHubPayload, resultPayload interface{}) error {
 	payload.Identifier = fmt.Sprintf("%d", c.getNextIdentifier())
 
+	_, span := c.startSpan(SpanInvoke)
+	if c.config.Tracer != nil {
+		span.SetAttribute(AttributeHub, payload.Hub)
+		span.SetAttribute(AttributeMethod, payload.Method)
+		span.SetAttribute(AttributeInvocationID, payload.Identifier)
+	}
+
+	start := time.Now()
+	err := c.callHub(payload, resultPayload)
+	c.measureInvocation(payload, time.Since(start), err)
+	endSpan(span, err)
+
+	return err
+}
+
+func (c *client) callHub(payload CallHubPayload, resultPayload interface{}) error {
 	var (
 		data []byte
 		err  error
@@ -47,7 +84,7 @@ func (c *client) CallHub(payload CallHubPayload, resultPayload interface{}) erro
 
 	//attempt to marshal the payload
 	if data, err = json.Marshal(payload); err != nil {
-		err = newCallHubError(
+		err = NewCallHubError(
 			fmt.Sprintf(
 				"Unable to marshal the callhub payload: %+v",
 				payload,
@@ -60,20 +97,23 @@ func (c *client) CallHub(payload CallHubPayload, resultPayload interface{}) erro
 	}
 
 	//set the response future channel
-	c.setResponseChan(payload.Identifier)
+	rc := c.setResponseChan(payload.Identifier)
 	//send the message payload to the signalr peer
 	if err = c.sendHubMessage(data); err != nil {
+		//nothing is coming back for an invocation that never left.
+		c.delResponseChan(payload.Identifier)
 		return err
 	}
 
 	var (
 		response *serverMessage
+		result   json.RawMessage
 	)
 
-	response = <-(c.responseChan(payload.Identifier))
+	response = <-rc
 
 	if response == nil {
-		err = newCallHubError(
+		err = NewCallHubError(
 			fmt.Sprintf("Call to method %s returned no result.", payload.Method),
 			nil,
 		)
@@ -81,7 +121,7 @@ func (c *client) CallHub(payload CallHubPayload, resultPayload interface{}) erro
 		return err
 	}
 	if response.Error != "" {
-		err = newCallHubError(
+		err = NewCallHubError(
 			"Error detected within responseChan payload.",
 			errors.New(response.Error),
 		)
@@ -89,8 +129,25 @@ func (c *client) CallHub(payload CallHubPayload, resultPayload interface{}) erro
 		return err
 	}
 
-	if err = json.Unmarshal(response.Result, resultPayload); err != nil {
-		err = newCallHubError(
+	//the caller only cares whether it worked.
+	if resultPayload == nil {
+		return nil
+	}
+
+	if result, err = c.decode(payload.Hub, payload.Method, response.Result); err != nil {
+		err = newArgumentDecodeError(
+			fmt.Sprintf("Unable to decode response: \n Method: %s \n response.Result: %s \n",
+				payload.Method,
+				string(response.Result),
+			),
+			err,
+		)
+		c.sendErr(err)
+		return err
+	}
+
+	if err = json.Unmarshal(result, resultPayload); err != nil {
+		err = NewCallHubError(
 			fmt.Sprintf("Unable to parse response: \n Method: %s \n response.Result: %s \n",
 				payload.Method,
 				string(response.Result),
@@ -108,8 +165,15 @@ func (c *client) sendHubMessage(data []byte) error {
 	c.socketWriteMutex.Lock()
 	defer c.socketWriteMutex.Unlock()
 
+	//a client that was never connected, or has been Reset, has no socket to write to.
+	if c.socket == nil {
+		err := NewSocketError("Unable to write message to socket hub", errors.New("websocket not connected"))
+		c.sendErr(err)
+		return err
+	}
+
 	if err := c.socket.WriteMessage(websocket.TextMessage, data); err != nil {
-		err = newSocketError(
+		err = NewSocketError(
 			"Unable to write message to socket hub",
 			err,
 		)
@@ -117,5 +181,11 @@ func (c *client) sendHubMessage(data []byte) error {
 		return err
 	}
 
+	atomic.AddUint64(&c.bytes.payloadOut, uint64(len(data)))
+	atomic.AddUint64(&c.traffic.messagesOut, 1)
+	c.recordFrame(FrameOut, data)
+	c.logFrameOut(data)
+	c.countFrame("out", len(data))
+
 	return nil
 }
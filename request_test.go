@@ -1,23 +1,31 @@
 package signalr
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
 )
 
 //this test is not a unit test... at all....
 
 func TestCallHub(t *testing.T) {
 	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+	server.Handle("c2", "GetAuthContext", func(args []json.RawMessage) (interface{}, error) {
+		return "challenge", nil
+	})
 
 	//config
 	cfg := Config{
 		Client: &http.Client{},
 		ConnectionURL: &url.URL{
-			Scheme: "https",
-			Host:   "socket.bittrex.com",
+			Scheme: "http",
+			Host:   server.URL().Host,
 		},
 		NegotiatePath: "signalr/negotiate",
 		ConnectPath:   "signalr/connect",
@@ -27,10 +35,17 @@ func TestCallHub(t *testing.T) {
 	//client
 	c := New(cfg).(*client)
 
-	c.heartbeatChan = make(chan Heartbeat, 5)
+	errs := c.ListenToErrors()
+	c.ListenToHeartbeat()
 
 	//connection
-	c.Connect([]string{"c2"})
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	for deadline := time.Now().Add(5 * time.Second); c.State() != Connected; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout connecting, in state %s", c.State())
+		}
+	}
 
 	var (
 		//callHub payload with empty string as argument
@@ -52,7 +67,7 @@ func TestCallHub(t *testing.T) {
 
 	//Assert
 	select {
-	case e := <-c.errChan:
+	case e := <-errs:
 		switch cast := e.(type) {
 		case CallHubError:
 			t.Logf("Callhub failed in an expected way: %s", cast.Error())
@@ -67,3 +82,36 @@ func TestCallHub(t *testing.T) {
 	}
 
 }
+
+func TestCallHubDiscardsResult(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+	server.Handle("c2", "SubscribeToExchangeDeltas", func(args []json.RawMessage) (interface{}, error) {
+		return map[string]bool{"Subscribed": true}, nil
+	})
+
+	c := New(Config{ConnectionURL: server.URL()}).(*client)
+	errs := c.ListenToErrors()
+	states := c.SubscribeToState()
+
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	waitForState(t, states, Connected)
+
+	//Act
+	err := c.CallHub(CallHubPayload{Hub: "c2", Method: "SubscribeToExchangeDeltas", Arguments: []interface{}{"BTC-ETH"}}, nil)
+
+	//Assert
+	if err != nil {
+		t.Errorf("expected a nil resultPayload to discard the result, found %v", err)
+	}
+
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, found %v", <-errs)
+	}
+
+	if stats := server.Stats(); stats.Invocations != 1 {
+		t.Errorf("expected the invocation to reach the server, found %+v", stats)
+	}
+}
@@ -0,0 +1,124 @@
+package signalr
+
+import (
+	"context"
+
+	"github.com/gorilla/websocket"
+)
+
+// session the lifetime of a single Connect call.  Reset cancels it and waits for Connect to return.
+type session struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startSession begin a new Connect lifetime.
+func (c *client) startSession() *session {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &session{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	c.sessionMutex.Lock()
+	c.session = s
+	c.sessionMutex.Unlock()
+
+	return s
+}
+
+// endSession mark the Connect lifetime over.
+func (c *client) endSession(s *session) {
+	s.cancel()
+
+	c.sessionMutex.Lock()
+	if c.session == s {
+		c.session = nil
+	}
+	c.sessionMutex.Unlock()
+
+	close(s.done)
+}
+
+// connecting true while a Connect is running.
+func (c *client) connecting() bool {
+	c.sessionMutex.Lock()
+	defer c.sessionMutex.Unlock()
+
+	return c.session != nil
+}
+
+// context the context of the running Connect, for token providers, requests and dials.  cancelled by Reset.
+func (c *client) context() context.Context {
+	c.sessionMutex.Lock()
+	defer c.sessionMutex.Unlock()
+
+	if c.session == nil {
+		return context.Background()
+	}
+
+	return c.session.ctx
+}
+
+// Reset tear down the transport, fail any pending CallHub invocations and forget the message cursor, returning the client
+// to Ready so Connect can be called again.  Subscriber channels and registered callbacks are kept.  Safe to call in any
+// state, including Broken, and while Connect is running: Connect returns once the teardown is complete.
+func (c *client) Reset() {
+	c.sessionMutex.Lock()
+	s := c.session
+	c.sessionMutex.Unlock()
+
+	if s != nil {
+		s.cancel()
+	}
+
+	c.setSocket(nil)
+
+	if s != nil {
+		<-s.done
+	}
+
+	//a dial that completed while Connect was winding down may have left a socket behind.
+	c.setSocket(nil)
+
+	c.clearResponseChans()
+	c.setNegotiated(nil)
+
+	if c.State() != Ready {
+		c.setState(Ready)
+	}
+}
+
+// setSocket make socket the live one, closing whatever it replaces.  nil just closes the live socket.
+func (c *client) setSocket(socket *websocket.Conn) {
+	c.socketWriteMutex.Lock()
+	defer c.socketWriteMutex.Unlock()
+
+	if c.socket != nil && c.socket != socket {
+		c.socket.Close()
+	}
+	c.socket = socket
+}
+
+// closeSocket close socket, forgetting it if it's still the live one.  run when the read loop is done with a socket, so a
+// dead one isn't left open while the client redials.
+func (c *client) closeSocket(socket *websocket.Conn) {
+	c.socketWriteMutex.Lock()
+	defer c.socketWriteMutex.Unlock()
+
+	socket.Close()
+	if c.socket == socket {
+		c.socket = nil
+	}
+}
+
+// currentSocket the live socket, or nil.
+func (c *client) currentSocket() *websocket.Conn {
+	c.socketWriteMutex.Lock()
+	defer c.socketWriteMutex.Unlock()
+
+	return c.socket
+}
@@ -0,0 +1,224 @@
+package signalr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+func waitForState(t *testing.T, states <-chan ConnectionState, want ConnectionState) {
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case state := <-states:
+			if state == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for state %s", want)
+		}
+	}
+}
+
+func TestResetBrokenClient(t *testing.T) {
+	//Assemble
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+	states := c.SubscribeToState()
+
+	c.Connect([]string{"c2"})
+	if c.State() != Broken {
+		t.Fatalf("expected broken client, found %s", c.State())
+	}
+
+	if _, ok := c.Connect([]string{"c2"}).(ConnectError); !ok {
+		t.Fatalf("expected broken client to refuse Connect")
+	}
+
+	//Act
+	c.Reset()
+
+	//Assert
+	if c.State() != Ready {
+		t.Errorf("expected ready after reset, found %s", c.State())
+	}
+
+	//the original subscription keeps working across the reset.
+	waitForState(t, states, Ready)
+
+	if _, ok := c.Connect([]string{"c2"}).(ConnectError); ok {
+		t.Errorf("reset client should accept Connect again")
+	}
+}
+
+func TestResetWhileConnected(t *testing.T) {
+	//Assemble
+	server := newPeerServer(peerConfig{})
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+	states := c.SubscribeToState()
+
+	connectErr := make(chan error, 1)
+	go func() {
+		connectErr <- c.Connect([]string{"c2"})
+	}()
+
+	waitForState(t, states, Connected)
+	c.updateMessageID("d-1")
+
+	callErr := make(chan error, 1)
+	go func() {
+		var result string
+		callErr <- c.CallHub(CallHubPayload{Hub: "c2", Method: "NeverAnswered"}, &result)
+	}()
+
+	//let the invocation get on the wire.
+	time.Sleep(100 * time.Millisecond)
+
+	//Act
+	finishesWithin(t, 5*time.Second, "Reset", c.Reset)
+
+	//Assert
+	select {
+	case err := <-connectErr:
+		if err == nil {
+			t.Errorf("expected Connect to return an error after reset")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Connect still running after reset")
+	}
+
+	select {
+	case err := <-callErr:
+		if err == nil {
+			t.Errorf("expected pending invocation to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending invocation not released by reset")
+	}
+
+	if c.State() != Ready {
+		t.Errorf("expected ready after reset, found %s", c.State())
+	}
+
+	if c.messageID != "" {
+		t.Errorf("expected message id to be cleared, found %q", c.messageID)
+	}
+}
+
+func TestCallHubAfterReset(t *testing.T) {
+	//Assemble
+	c, closeServer := dialPeerServer(t, Config{}, peerConfig{})
+	defer closeServer()
+	c.Reset()
+
+	//Act
+	err := c.CallHub(CallHubPayload{Hub: "c2", Method: "QueryExchangeState"}, nil)
+
+	//Assert
+	if _, ok := err.(SocketError); !ok {
+		t.Errorf("expected a SocketError once the socket is gone, found %T: %v", err, err)
+	}
+}
+
+func TestResetDuringSlowReconnect(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	c := New(Config{ConnectionURL: server.URL()}).(*client)
+	states := c.SubscribeToState()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Connect([]string{"c2"}) }()
+	waitForState(t, states, Connected)
+
+	server.SetLatency(300 * time.Millisecond)
+	server.Disconnect()
+	waitForState(t, states, Reconnecting)
+
+	//CallHub keeps reaching for the socket while the reconnect swaps it.
+	stop := make(chan struct{})
+	calling := make(chan struct{})
+	go func() {
+		defer close(calling)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.sendHubMessage([]byte(`{"H":"c2","M":"Ping","I":"0"}`))
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	//Act
+	//past the first backoff, with the dial held up by the server's latency.
+	time.Sleep(1100 * time.Millisecond)
+	c.Reset()
+	close(stop)
+	<-calling
+
+	//Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Connect still running after Reset")
+	}
+
+	if c.State() != Ready || c.currentSocket() != nil {
+		t.Errorf("expected Ready without a socket, found %s with %v", c.State(), c.currentSocket())
+	}
+
+	for deadline := time.Now().Add(2 * time.Second); server.Connected() != 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the dialed socket to be closed, server still has %d connected", server.Connected())
+		}
+	}
+}
+
+func TestResetWhileDeliveryBlocked(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	c := New(Config{ConnectionURL: server.URL()}).(*client)
+	messages := c.ListenToHubResponses()
+	states := c.SubscribeToState()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Connect([]string{"c2"}) }()
+	waitForState(t, states, Connected)
+
+	server.Push("c2", "uE", 1)
+	server.Push("c2", "uE", 2)
+	<-messages
+
+	//Act
+	//the consumer resets instead of taking the second message, which the read loop is blocked handing over.
+	finishesWithin(t, 2*time.Second, "Reset", c.Reset)
+
+	//Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Connect still running after Reset")
+	}
+
+	if c.State() != Ready {
+		t.Errorf("expected ready after reset, found %s", c.State())
+	}
+}
@@ -0,0 +1,644 @@
+// Package signalrmock provides a scriptable, recording fake of signalr.Connection for unit tests of code built on the
+// client.  Tests script hub method results, push messages, heartbeats, errors and state changes, and inspect the
+// invocations made:
+//
+//	conn := signalrmock.New()
+//	conn.Returns("c2", "QueryExchangeState", state)
+//	go conn.Connect([]string{"c2"})
+//	conn.PushMessage("c2", "uE", delta)
+//	...
+//	calls := conn.Invocations()
+//
+// Connect blocks until Reset or Break, OnReconnected, OnGapDetected and friends fire on the same events as on the real
+// client, and CallHub and SendPing fail with the client's SocketError and CallHubError.  It is not a faithful copy in
+// two ways, both to keep tests simple: callbacks run synchronously in the goroutine that pushed the event, so they've
+// run by the time a push returns, and every subscription channel holds 16 events and never blocks, dropping and
+// counting what doesn't fit.  The real hub response channel is unbuffered and blocks the read loop by default, so code
+// that relies on the mock's buffer may stall against a live peer.
+package signalrmock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2"
+)
+
+// defaultBufferSize capacity of each subscription channel.
+const defaultBufferSize = 16
+
+// errNotConnected why CallHub and SendPing fail outside the Connected state, where the real client has no socket.
+var errNotConnected = errors.New("websocket not connected")
+
+// Handler scripted hub method, called with the invocation as sent.  result is marshaled to JSON and decoded into the
+// caller's result, as a response from the peer would be.  A non-nil err is returned to the caller as a CallHubError, as
+// an error from the peer would be.
+type Handler func(payload signalr.CallHubPayload) (result interface{}, err error)
+
+// Connection fake signalr.Connection.  Create with New.
+type Connection struct {
+	//counters first, so they stay 64-bit aligned for atomic access on 32-bit platforms.
+	messagesIn  uint64
+	messagesOut uint64
+	reconnects  uint64
+	dropped     signalr.DroppedEvents
+
+	mutex       sync.Mutex
+	state       signalr.ConnectionState
+	hubs        []string
+	handlers    map[string]Handler
+	invocations []signalr.CallHubPayload
+	nextID      int
+	connectErr  error
+	hooks       []*signalr.ConnectedHook
+
+	//stop ends the running Connect with its error; stopped is closed once it has returned.
+	stop    chan error
+	stopped chan struct{}
+
+	errs       feed
+	messages   feed
+	heartbeats feed
+	states     feed
+	received   feed
+	closed     feed
+}
+
+var _ signalr.Connection = (*Connection)(nil)
+
+// New a fake connection in the Ready state, with nothing scripted.
+func New() *Connection {
+	return &Connection{
+		state:    signalr.Ready,
+		handlers: map[string]Handler{},
+	}
+}
+
+// Handle script hub.method.  Names are case insensitive, as on the peer.
+func (c *Connection) Handle(hub string, method string, handler Handler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.handlers[methodKey(hub, method)] = handler
+}
+
+// Returns script hub.method to succeed with result.
+func (c *Connection) Returns(hub string, method string, result interface{}) {
+	c.Handle(hub, method, func(signalr.CallHubPayload) (interface{}, error) {
+		return result, nil
+	})
+}
+
+// Fails script hub.method to fail with err.
+func (c *Connection) Fails(hub string, method string, err error) {
+	c.Handle(hub, method, func(signalr.CallHubPayload) (interface{}, error) {
+		return nil, err
+	})
+}
+
+// FailConnect make the next Connect or Resume fail with err, leaving the connection Broken.  nil connects again.
+func (c *Connection) FailConnect(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.connectErr = err
+}
+
+// Invocations every CallHub made so far, connected or not, in order, with the identifiers they were given.
+func (c *Connection) Invocations() []signalr.CallHubPayload {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]signalr.CallHubPayload(nil), c.invocations...)
+}
+
+// Hubs the hubs passed to the last Connect or Resume.
+func (c *Connection) Hubs() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]string(nil), c.hubs...)
+}
+
+// Push deliver a hub message to subscribers, with the heartbeat the real client sends alongside.
+func (c *Connection) Push(payload signalr.MessageDataPayload) {
+	frame, _ := json.Marshal(map[string]interface{}{"M": []signalr.MessageDataPayload{payload}})
+
+	atomic.AddUint64(&c.messagesIn, 1)
+	c.received.publish(json.RawMessage(frame))
+	c.deliver(payload)
+}
+
+// deliver hand a hub message to subscribers, with its heartbeat, without counting it as traffic.
+func (c *Connection) deliver(payload signalr.MessageDataPayload) {
+	c.messages.publish(payload)
+	c.SendHeartbeat(signalr.HeartbeatEvent{
+		Kind:       signalr.HeartbeatMessage,
+		ReceivedAt: time.Now(),
+		Detail:     "Heartbeat refreshed by subscription signal.",
+	})
+}
+
+// PushMessage Push a hub message, marshaling args.
+func (c *Connection) PushMessage(hub string, method string, args ...interface{}) error {
+	payload := signalr.MessageDataPayload{HubName: hub, Method: method, Arguments: []json.RawMessage{}}
+
+	for _, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		payload.Arguments = append(payload.Arguments, data)
+	}
+
+	c.Push(payload)
+
+	return nil
+}
+
+// SendHeartbeat deliver a heartbeat to subscribers.
+func (c *Connection) SendHeartbeat(event signalr.HeartbeatEvent) {
+	c.heartbeats.publish(event)
+}
+
+// SendError deliver an error to subscribers.  GapDetected and ConnectionSlow also reach OnGapDetected and
+// OnConnectionSlow, as they do from the real client.
+func (c *Connection) SendError(err error) {
+	c.errs.publish(err)
+}
+
+// SetState move to state, with cause as the reason, notifying subscribers and running OnConnected hooks on Connected.
+// Any transition is allowed, so tests can script sequences the real client would only reach through failures.
+func (c *Connection) SetState(state signalr.ConnectionState, cause error) {
+	c.mutex.Lock()
+	change := signalr.StateChange{From: c.state, To: state, Cause: cause, At: time.Now()}
+	c.state = state
+	hooks := append([]*signalr.ConnectedHook(nil), c.hooks...)
+	c.mutex.Unlock()
+
+	if change.From == signalr.Reconnecting && change.To == signalr.Connected {
+		atomic.AddUint64(&c.reconnects, 1)
+	}
+
+	c.states.publish(change)
+
+	if change.To == signalr.Connected {
+		for _, hook := range hooks {
+			if err := (*hook)(context.Background(), c); err != nil {
+				c.SendError(err)
+			}
+		}
+	}
+}
+
+// Break end a running Connect with err, leaving the connection Broken, as running out of dial attempts would.
+func (c *Connection) Break(err error) {
+	c.SetState(signalr.Broken, err)
+	c.end(err)
+}
+
+// State implement signalr.Connection
+func (c *Connection) State() signalr.ConnectionState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.state
+}
+
+// Connect implement signalr.Connection.  Moves through Connecting to Connected, then blocks until Reset or Break.
+func (c *Connection) Connect(hubs []string) error {
+	c.mutex.Lock()
+	if c.state == signalr.Broken {
+		c.mutex.Unlock()
+		return signalr.ConnectError("Client in broken state.  Call Reset or create new client instance.")
+	}
+
+	if c.stop != nil {
+		c.mutex.Unlock()
+		return signalr.ConnectError("Already connected.")
+	}
+
+	c.hubs = append([]string(nil), hubs...)
+	connectErr := c.connectErr
+	stop, stopped := make(chan error, 1), make(chan struct{})
+	c.stop, c.stopped = stop, stopped
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		c.stop, c.stopped = nil, nil
+		c.mutex.Unlock()
+		close(stopped)
+	}()
+
+	c.SetState(signalr.Connecting, nil)
+
+	if connectErr != nil {
+		c.SendError(connectErr)
+		c.SetState(signalr.Broken, connectErr)
+		c.closed.publish(connectErr)
+		return connectErr
+	}
+
+	c.SetState(signalr.Connected, nil)
+
+	err := <-stop
+	c.closed.publish(err)
+
+	return err
+}
+
+// Resume implement signalr.Connection, as Connect.
+func (c *Connection) Resume(hubs []string) error {
+	return c.Connect(hubs)
+}
+
+// Reset implement signalr.Connection.  Ends a running Connect and returns to Ready.
+func (c *Connection) Reset() {
+	c.end(signalr.NewBrokenWebSocketError("handleSocketCommunication", context.Canceled))
+	c.SetState(signalr.Ready, nil)
+}
+
+// end stop a running Connect with err and wait for it to return.
+func (c *Connection) end(err error) {
+	c.mutex.Lock()
+	stop, stopped := c.stop, c.stopped
+	c.mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	select {
+	case stop <- err:
+	default:
+	}
+	<-stopped
+}
+
+// CallHub implement signalr.Connection.  Records the invocation, then answers it from the script.  Unscripted methods
+// fail as the peer fails a method it can't resolve.  Errors are also sent to error subscribers, as the real client does.
+func (c *Connection) CallHub(payload signalr.CallHubPayload, resultPayload interface{}) error {
+	c.mutex.Lock()
+	c.nextID++
+	payload.Identifier = strconv.Itoa(c.nextID)
+	c.invocations = append(c.invocations, payload)
+	handler, scripted := c.handlers[methodKey(payload.Hub, payload.Method)]
+	connected := c.state == signalr.Connected
+	c.mutex.Unlock()
+
+	err := c.callHub(payload, resultPayload, handler, scripted, connected)
+	if err != nil {
+		c.SendError(err)
+	}
+
+	return err
+}
+
+func (c *Connection) callHub(
+	payload signalr.CallHubPayload,
+	resultPayload interface{},
+	handler Handler,
+	scripted bool,
+	connected bool,
+) error {
+	if !connected {
+		return signalr.NewSocketError("Unable to write message to socket hub", errNotConnected)
+	}
+
+	atomic.AddUint64(&c.messagesOut, 1)
+
+	if !scripted {
+		return signalr.NewCallHubError(
+			"Error detected within responseChan payload.",
+			fmt.Errorf("'%s' method could not be resolved on hub '%s'.", payload.Method, payload.Hub),
+		)
+	}
+
+	result, err := handler(payload)
+	if err != nil {
+		return signalr.NewCallHubError("Error detected within responseChan payload.", err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return signalr.NewCallHubError(
+			fmt.Sprintf("signalrmock: unable to marshal scripted result for %s.%s", payload.Hub, payload.Method),
+			err,
+		)
+	}
+
+	if resultPayload == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(data, resultPayload); err != nil {
+		return signalr.NewCallHubError(
+			fmt.Sprintf("Unable to parse response: \n Method: %s \n response.Result: %s \n", payload.Method, string(data)),
+			err,
+		)
+	}
+
+	return nil
+}
+
+// SendPing implement signalr.Connection.  Instant when connected.
+func (c *Connection) SendPing() (time.Duration, error) {
+	if c.State() != signalr.Connected {
+		err := signalr.NewSocketError("Unable to send ping to socket hub", errNotConnected)
+		c.SendError(err)
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// Replay implement signalr.Connection.  Delivers the hub messages of the received frames in an NDJSONRecorder recording,
+// as fast as possible.  As on the real client, replayed messages aren't counted in Stats or seen by OnReceived, and
+// replay fails while Connect is running.
+func (c *Connection) Replay(ctx context.Context, r io.Reader, speed float64) error {
+	decoder := json.NewDecoder(r)
+
+	c.mutex.Lock()
+	connecting := c.stop != nil
+	c.mutex.Unlock()
+
+	if connecting {
+		return errors.New("signalrmock: unable to replay while Connect is running")
+	}
+
+	for line := 1; ; line++ {
+		var frame signalr.Frame
+		if err := decoder.Decode(&frame); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("signalrmock: unable to read recorded frame %d: %w", line, err)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var message struct {
+			Data []signalr.MessageDataPayload `json:"M"`
+		}
+		if frame.Direction != signalr.FrameIn || json.Unmarshal([]byte(frame.Data), &message) != nil {
+			continue
+		}
+
+		for _, payload := range message.Data {
+			c.deliver(payload)
+		}
+	}
+}
+
+// ListenToErrors implement signalr.Connection
+func (c *Connection) ListenToErrors() <-chan error {
+	ch, _ := c.ErrorSubscription()
+	return ch
+}
+
+// ListenToHubResponses implement signalr.Connection
+func (c *Connection) ListenToHubResponses() <-chan signalr.MessageDataPayload {
+	ch, _ := c.HubResponseSubscription()
+	return ch
+}
+
+// ListenToHeartbeat implement signalr.Connection
+func (c *Connection) ListenToHeartbeat() <-chan signalr.Heartbeat {
+	ch, _ := c.HeartbeatSubscription()
+	return ch
+}
+
+// SubscribeToState implement signalr.Connection
+func (c *Connection) SubscribeToState() <-chan signalr.ConnectionState {
+	ch, _ := c.StateSubscription()
+	return ch
+}
+
+// ErrorSubscription implement signalr.Connection
+func (c *Connection) ErrorSubscription() (<-chan error, signalr.UnsubscribeFunc) {
+	ch := make(chan error, defaultBufferSize)
+
+	return ch, c.errs.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(error):
+			default:
+				atomic.AddUint64(&c.dropped.Errors, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// HubResponseSubscription implement signalr.Connection
+func (c *Connection) HubResponseSubscription() (<-chan signalr.MessageDataPayload, signalr.UnsubscribeFunc) {
+	ch := make(chan signalr.MessageDataPayload, defaultBufferSize)
+
+	return ch, c.messages.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(signalr.MessageDataPayload):
+			default:
+				atomic.AddUint64(&c.dropped.Messages, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// HeartbeatSubscription implement signalr.Connection
+func (c *Connection) HeartbeatSubscription() (<-chan signalr.Heartbeat, signalr.UnsubscribeFunc) {
+	ch := make(chan signalr.Heartbeat, defaultBufferSize)
+
+	return ch, c.heartbeats.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(signalr.HeartbeatEvent).Legacy():
+			default:
+				atomic.AddUint64(&c.dropped.Heartbeats, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// HeartbeatEventSubscription implement signalr.Connection
+func (c *Connection) HeartbeatEventSubscription() (<-chan signalr.HeartbeatEvent, signalr.UnsubscribeFunc) {
+	ch := make(chan signalr.HeartbeatEvent, defaultBufferSize)
+
+	return ch, c.heartbeats.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(signalr.HeartbeatEvent):
+			default:
+				atomic.AddUint64(&c.dropped.Heartbeats, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// StateSubscription implement signalr.Connection
+func (c *Connection) StateSubscription() (<-chan signalr.ConnectionState, signalr.UnsubscribeFunc) {
+	ch := make(chan signalr.ConnectionState, defaultBufferSize)
+
+	return ch, c.states.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(signalr.StateChange).To:
+			default:
+				atomic.AddUint64(&c.dropped.States, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// StateChangeSubscription implement signalr.Connection
+func (c *Connection) StateChangeSubscription() (<-chan signalr.StateChange, signalr.UnsubscribeFunc) {
+	ch := make(chan signalr.StateChange, defaultBufferSize)
+
+	return ch, c.states.subscribe(
+		func(event interface{}) {
+			select {
+			case ch <- event.(signalr.StateChange):
+			default:
+				atomic.AddUint64(&c.dropped.States, 1)
+			}
+		},
+		func() { close(ch) },
+	)
+}
+
+// OnStateChanged implement signalr.Connection
+func (c *Connection) OnStateChanged(handler func(old signalr.ConnectionState, new signalr.ConnectionState)) signalr.UnsubscribeFunc {
+	return c.states.subscribe(func(event interface{}) {
+		change := event.(signalr.StateChange)
+		handler(change.From, change.To)
+	}, nil)
+}
+
+// OnReconnecting implement signalr.Connection
+func (c *Connection) OnReconnecting(handler func()) signalr.UnsubscribeFunc {
+	return c.states.subscribe(func(event interface{}) {
+		if event.(signalr.StateChange).To == signalr.Reconnecting {
+			handler()
+		}
+	}, nil)
+}
+
+// OnReconnected implement signalr.Connection
+func (c *Connection) OnReconnected(handler func()) signalr.UnsubscribeFunc {
+	return c.states.subscribe(func(event interface{}) {
+		if change := event.(signalr.StateChange); change.From == signalr.Reconnecting && change.To == signalr.Connected {
+			handler()
+		}
+	}, nil)
+}
+
+// OnClosed implement signalr.Connection
+func (c *Connection) OnClosed(handler func(error)) signalr.UnsubscribeFunc {
+	return c.closed.subscribe(func(event interface{}) {
+		err, _ := event.(error)
+		handler(err)
+	}, nil)
+}
+
+// OnError implement signalr.Connection
+func (c *Connection) OnError(handler func(error)) signalr.UnsubscribeFunc {
+	return c.errs.subscribe(func(event interface{}) {
+		handler(event.(error))
+	}, nil)
+}
+
+// OnReceived implement signalr.Connection
+func (c *Connection) OnReceived(handler func(json.RawMessage)) signalr.UnsubscribeFunc {
+	return c.received.subscribe(func(event interface{}) {
+		handler(event.(json.RawMessage))
+	}, nil)
+}
+
+// OnHeartbeat implement signalr.Connection
+func (c *Connection) OnHeartbeat(handler func(signalr.HeartbeatEvent)) signalr.UnsubscribeFunc {
+	return c.heartbeats.subscribe(func(event interface{}) {
+		handler(event.(signalr.HeartbeatEvent))
+	}, nil)
+}
+
+// OnConnected implement signalr.Connection.  Hooks run once, synchronously, on each move to Connected; their errors are
+// sent to error subscribers.
+func (c *Connection) OnConnected(hook signalr.ConnectedHook) signalr.UnsubscribeFunc {
+	entry := &hook
+
+	c.mutex.Lock()
+	c.hooks = append(c.hooks, entry)
+	c.mutex.Unlock()
+
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		for i, h := range c.hooks {
+			if h == entry {
+				c.hooks = append(c.hooks[:i:i], c.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// OnGapDetected implement signalr.Connection
+func (c *Connection) OnGapDetected(handler func(signalr.GapDetected)) signalr.UnsubscribeFunc {
+	return c.errs.subscribe(func(event interface{}) {
+		if gap, ok := event.(signalr.GapDetected); ok {
+			handler(gap)
+		}
+	}, nil)
+}
+
+// OnConnectionSlow implement signalr.Connection
+func (c *Connection) OnConnectionSlow(handler func()) signalr.UnsubscribeFunc {
+	return c.errs.subscribe(func(event interface{}) {
+		if _, ok := event.(signalr.ConnectionSlow); ok {
+			handler()
+		}
+	}, nil)
+}
+
+// CompressionStats implement signalr.Connection.  Nothing crosses a wire, so always zero.
+func (c *Connection) CompressionStats() signalr.CompressionStats {
+	return signalr.CompressionStats{}
+}
+
+// DroppedEvents implement signalr.Connection
+func (c *Connection) DroppedEvents() signalr.DroppedEvents {
+	return signalr.DroppedEvents{
+		Messages:   atomic.LoadUint64(&c.dropped.Messages),
+		Errors:     atomic.LoadUint64(&c.dropped.Errors),
+		Heartbeats: atomic.LoadUint64(&c.dropped.Heartbeats),
+		States:     atomic.LoadUint64(&c.dropped.States),
+	}
+}
+
+// Stats implement signalr.Connection.  Counts pushes in, connected invocations out, and reconnects.
+func (c *Connection) Stats() signalr.Stats {
+	return signalr.Stats{
+		MessagesIn:  atomic.LoadUint64(&c.messagesIn),
+		MessagesOut: atomic.LoadUint64(&c.messagesOut),
+		Reconnects:  atomic.LoadUint64(&c.reconnects),
+	}
+}
+
+func methodKey(hub string, method string) string {
+	return strings.ToLower(hub) + "." + strings.ToLower(method)
+}
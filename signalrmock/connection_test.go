@@ -0,0 +1,218 @@
+package signalrmock_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2"
+	"gitlab.com/techviking/signalr/v2/signalrmock"
+)
+
+// connect start Connect in the background, returning once it's Connected.
+func connect(t *testing.T, conn *signalrmock.Connection) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- conn.Connect([]string{"c2"}) }()
+
+	for deadline := time.Now().Add(time.Second); conn.State() != signalr.Connected; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout connecting, in state %s", conn.State())
+		}
+	}
+
+	return done
+}
+
+func TestCallHubScriptedAndRecorded(t *testing.T) {
+	//Assemble
+	conn := signalrmock.New()
+	conn.Returns("C2", "QueryExchangeState", map[string]int{"Nonce": 7})
+	conn.Fails("c2", "SubscribeToExchangeDeltas", errors.New("unknown market"))
+	errs := conn.ListenToErrors()
+	connect(t, conn)
+	defer conn.Reset()
+
+	//Act
+	var state struct{ Nonce int }
+	okErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "queryExchangeState", Arguments: []interface{}{"BTC-ETH"}}, &state)
+	failErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "SubscribeToExchangeDeltas"}, nil)
+	missingErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "Missing"}, nil)
+
+	//Assert
+	if okErr != nil || state.Nonce != 7 {
+		t.Errorf("expected the scripted result, found %+v, %v", state, okErr)
+	}
+
+	if _, ok := failErr.(signalr.CallHubError); !ok || !strings.Contains(failErr.Error(), "unknown market") {
+		t.Errorf("expected the scripted error as a CallHubError, found %T: %v", failErr, failErr)
+	}
+
+	if _, ok := missingErr.(signalr.CallHubError); !ok || !strings.Contains(missingErr.Error(), "could not be resolved") {
+		t.Errorf("expected an unscripted method to fail like the peer, found %T: %v", missingErr, missingErr)
+	}
+
+	if len(errs) != 2 {
+		t.Errorf("expected both failures on the error channel, found %d", len(errs))
+	}
+
+	calls := conn.Invocations()
+	if len(calls) != 3 || calls[0].Identifier != "1" || calls[0].Arguments[0] != "BTC-ETH" {
+		t.Errorf("expected the invocations recorded in order, found %+v", calls)
+	}
+}
+
+func TestCallHubNotConnected(t *testing.T) {
+	conn := signalrmock.New()
+	conn.Returns("c2", "QueryExchangeState", nil)
+
+	if err := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "QueryExchangeState"}, nil); err == nil {
+		t.Error("expected CallHub to fail before Connect")
+	} else if _, ok := err.(signalr.SocketError); !ok {
+		t.Errorf("expected a SocketError before Connect, found %T: %v", err, err)
+	}
+
+	if _, err := conn.SendPing(); err == nil {
+		t.Error("expected SendPing to fail before Connect")
+	} else if _, ok := err.(signalr.SocketError); !ok {
+		t.Errorf("expected a SocketError from SendPing before Connect, found %T: %v", err, err)
+	}
+}
+
+func TestPushes(t *testing.T) {
+	//Assemble
+	conn := signalrmock.New()
+	messages := conn.ListenToHubResponses()
+	heartbeats, unsubscribe := conn.HeartbeatEventSubscription()
+	defer unsubscribe()
+
+	var gap signalr.GapDetected
+	conn.OnGapDetected(func(g signalr.GapDetected) { gap = g })
+
+	//Act
+	conn.PushMessage("c2", "uE", map[string]int{"Nonce": 8})
+	conn.SendError(signalr.GapDetected{LastMessageID: "d-3"})
+
+	//Assert
+	msg := <-messages
+	if msg.HubName != "c2" || msg.Method != "uE" || string(msg.Arguments[0]) != `{"Nonce":8}` {
+		t.Errorf("unexpected pushed message %+v", msg)
+	}
+
+	if hb := <-heartbeats; hb.Kind != signalr.HeartbeatMessage {
+		t.Errorf("expected a message heartbeat with the push, found %s", hb.Kind)
+	}
+
+	if gap.LastMessageID != "d-3" {
+		t.Errorf("expected OnGapDetected to have run by the time SendError returned, found %+v", gap)
+	}
+}
+
+func TestStateLifecycle(t *testing.T) {
+	//Assemble
+	conn := signalrmock.New()
+	changes, unsubscribe := conn.StateChangeSubscription()
+	defer unsubscribe()
+
+	//the first hook run is on Connect's goroutine.
+	var reconnected, hooked int32
+	conn.OnReconnected(func() { atomic.AddInt32(&reconnected, 1) })
+	conn.OnConnected(func(ctx context.Context, c signalr.Connection) error {
+		atomic.AddInt32(&hooked, 1)
+		return nil
+	})
+
+	var closedWith error
+	conn.OnClosed(func(err error) { closedWith = err })
+
+	//Act
+	done := connect(t, conn)
+	conn.SetState(signalr.Reconnecting, errors.New("read: connection reset"))
+	conn.SetState(signalr.Connected, nil)
+	broken := errors.New("max retries")
+	conn.Break(broken)
+
+	//Assert
+	if err := <-done; err != broken {
+		t.Errorf("expected Connect to return the Break error, found %v", err)
+	}
+
+	if closedWith != broken {
+		t.Errorf("expected OnClosed with the Break error, found %v", closedWith)
+	}
+
+	expected := []signalr.ConnectionState{
+		signalr.Connecting, signalr.Connected, signalr.Reconnecting, signalr.Connected, signalr.Broken,
+	}
+	for _, want := range expected {
+		if change := <-changes; change.To != want {
+			t.Errorf("expected a change to %s, found %+v", want, change)
+		}
+	}
+
+	if atomic.LoadInt32(&reconnected) != 1 || atomic.LoadInt32(&hooked) != 2 || conn.Stats().Reconnects != 1 {
+		t.Errorf("expected 1 reconnect and 2 hook runs, found %d and %d", reconnected, hooked)
+	}
+
+	if err := conn.Connect(nil); err == nil {
+		t.Error("expected Connect to refuse a Broken connection until Reset")
+	}
+
+	conn.Reset()
+	if conn.State() != signalr.Ready {
+		t.Errorf("expected Reset to return to Ready, found %s", conn.State())
+	}
+}
+
+func TestFailConnect(t *testing.T) {
+	conn := signalrmock.New()
+	refused := errors.New("negotiate: 503")
+	conn.FailConnect(refused)
+
+	if err := conn.Connect([]string{"c2"}); err != refused || conn.State() != signalr.Broken {
+		t.Errorf("expected Connect to fail with the scripted error and break, found %v in %s", err, conn.State())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	//Assemble
+	var buf bytes.Buffer
+	recorder := signalr.NewNDJSONRecorder(&buf)
+	recorder.Record(signalr.Frame{Direction: signalr.FrameIn, Data: `{"C":"d-1","M":[{"H":"c2","M":"uE","A":[1]}]}`})
+	recorder.Record(signalr.Frame{Direction: signalr.FrameOut, Data: `{"H":"c2","M":"QueryExchangeState","I":"1"}`})
+
+	conn := signalrmock.New()
+	messages := conn.ListenToHubResponses()
+
+	//Act
+	err := conn.Replay(context.Background(), &buf, 0)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected the one received hub message, found %d", len(messages))
+	}
+
+	if msg := <-messages; msg.Method != "uE" {
+		t.Errorf("unexpected replayed message %+v", msg)
+	}
+}
+
+func TestDroppedWhenFull(t *testing.T) {
+	conn := signalrmock.New()
+	conn.ListenToErrors()
+
+	for i := 0; i < 20; i++ {
+		conn.SendError(errors.New("boom"))
+	}
+
+	if got := conn.DroppedEvents().Errors; got != 4 {
+		t.Errorf("expected the errors beyond the buffer to be dropped and counted, found %d", got)
+	}
+}
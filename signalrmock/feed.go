@@ -0,0 +1,71 @@
+package signalrmock
+
+import (
+	"sync"
+
+	"gitlab.com/techviking/signalr/v2"
+)
+
+// subscriber one channel or callback on a feed.  Channel deliveries never block, so they run with mutex held, which
+// keeps a concurrent unsubscribe from closing the channel mid send.  Callbacks run without it.
+type subscriber struct {
+	mutex   sync.Mutex
+	active  bool
+	deliver func(event interface{})
+	close   func()
+}
+
+// feed fans events out, in order, to every subscriber.
+type feed struct {
+	mutex       sync.Mutex
+	subscribers []*subscriber
+}
+
+// subscribe add a subscriber.  closeFn, if set, marks a channel subscriber and is run on unsubscribe.
+func (f *feed) subscribe(deliver func(event interface{}), closeFn func()) signalr.UnsubscribeFunc {
+	sub := &subscriber{active: true, deliver: deliver, close: closeFn}
+
+	f.mutex.Lock()
+	f.subscribers = append(f.subscribers, sub)
+	f.mutex.Unlock()
+
+	return func() {
+		f.mutex.Lock()
+		for i, s := range f.subscribers {
+			if s == sub {
+				f.subscribers = append(f.subscribers[:i:i], f.subscribers[i+1:]...)
+				break
+			}
+		}
+		f.mutex.Unlock()
+
+		sub.mutex.Lock()
+		defer sub.mutex.Unlock()
+
+		if sub.active {
+			sub.active = false
+			if sub.close != nil {
+				sub.close()
+			}
+		}
+	}
+}
+
+func (f *feed) publish(event interface{}) {
+	f.mutex.Lock()
+	subscribers := append([]*subscriber(nil), f.subscribers...)
+	f.mutex.Unlock()
+
+	for _, sub := range subscribers {
+		sub.mutex.Lock()
+		active := sub.active
+		if active && sub.close != nil {
+			sub.deliver(event)
+		}
+		sub.mutex.Unlock()
+
+		if active && sub.close == nil {
+			sub.deliver(event)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+module gitlab.com/techviking/signalr/v2/signalrprom
+
+go 1.16
+
+require github.com/prometheus/client_golang v1.9.0
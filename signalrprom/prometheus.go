@@ -0,0 +1,180 @@
+// Package signalrprom exports signalr client telemetry to Prometheus.  It lives in a module of its own, so the client
+// doesn't drag the Prometheus libraries into projects that don't use them.
+//
+//	cfg.Metrics = signalrprom.New(prometheus.DefaultRegisterer)
+package signalrprom
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// description help text and, for histograms, buckets of one of the client's metrics.
+type description struct {
+	help    string
+	buckets []float64
+}
+
+// descriptions the client's metrics, by name.  Names missing here get generic help and prometheus.DefBuckets.
+var descriptions = map[string]description{
+	"signalr_connects_total": {
+		help: "Websocket connections established, by kind: connect for a new connection, reconnect for a resumed one.",
+	},
+	"signalr_reconnect_attempts_total": {
+		help: "Websocket dials made trying to resume a dropped connection.",
+	},
+	"signalr_state_duration_seconds": {
+		help: "Seconds the connection spent in a state, observed as it leaves that state.",
+		//1 second to 18 hours: a healthy connection stays Connected for hours.
+		buckets: prometheus.ExponentialBuckets(1, 4, 9),
+	},
+	"signalr_frames_total": {
+		help: "Websocket frames sent to (out) and received from (in) the SignalR peer.",
+	},
+	"signalr_bytes_total": {
+		help: "Websocket frame payload bytes sent to (out) and received from (in) the SignalR peer, before compression.",
+	},
+	"signalr_invocation_duration_seconds": {
+		help: "Seconds from sending a CallHub invocation to receiving its result or error, by hub and method.",
+		//5 milliseconds to 10 seconds.
+		buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	},
+	"signalr_invocation_failures_total": {
+		help: "CallHub invocations that failed, by hub, method and error type.",
+	},
+	"signalr_pending_invocations": {
+		help: "CallHub invocations sent and still waiting on a result.",
+	},
+	"signalr_dropped_total": {
+		help: "Events discarded rather than delivered, by kind: messages, errors, heartbeats, states, duplicates or callbacks.",
+	},
+}
+
+// Metrics signalr.Metrics implementation backed by Prometheus collectors, registered on first use of each metric name.
+type Metrics struct {
+	registerer prometheus.Registerer
+
+	mutex      sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// New register collectors with registerer as the client reports them.
+func New(registerer prometheus.Registerer) *Metrics {
+	return &Metrics{
+		registerer: registerer,
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+	}
+}
+
+// IncCounter implement signalr.Metrics
+func (m *Metrics) IncCounter(name string, labels map[string]string, value float64) {
+	if counter, err := m.counter(name, labels).GetMetricWith(labels); err == nil {
+		counter.Add(value)
+	}
+}
+
+// SetGauge implement signalr.Metrics
+func (m *Metrics) SetGauge(name string, labels map[string]string, value float64) {
+	if gauge, err := m.gauge(name, labels).GetMetricWith(labels); err == nil {
+		gauge.Set(value)
+	}
+}
+
+// ObserveHistogram implement signalr.Metrics
+func (m *Metrics) ObserveHistogram(name string, labels map[string]string, value float64) {
+	if histogram, err := m.histogram(name, labels).GetMetricWith(labels); err == nil {
+		histogram.Observe(value)
+	}
+}
+
+func (m *Metrics) counter(name string, labels map[string]string) *prometheus.CounterVec {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if vec, ok := m.counters[name]; ok {
+		return vec
+	}
+
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help(name)}, labelNames(labels))
+	vec = m.register(vec).(*prometheus.CounterVec)
+	m.counters[name] = vec
+
+	return vec
+}
+
+func (m *Metrics) gauge(name string, labels map[string]string) *prometheus.GaugeVec {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if vec, ok := m.gauges[name]; ok {
+		return vec
+	}
+
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help(name)}, labelNames(labels))
+	vec = m.register(vec).(*prometheus.GaugeVec)
+	m.gauges[name] = vec
+
+	return vec
+}
+
+func (m *Metrics) histogram(name string, labels map[string]string) *prometheus.HistogramVec {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if vec, ok := m.histograms[name]; ok {
+		return vec
+	}
+
+	vec := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{Name: name, Help: help(name), Buckets: buckets(name)},
+		labelNames(labels),
+	)
+	vec = m.register(vec).(*prometheus.HistogramVec)
+	m.histograms[name] = vec
+
+	return vec
+}
+
+// register add collector to the registerer, reusing the one already there if several clients share a registry.
+// Any other registration failure leaves collector working but unexported rather than failing the client.
+func (m *Metrics) register(collector prometheus.Collector) prometheus.Collector {
+	if err := m.registerer.Register(collector); err != nil {
+		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return already.ExistingCollector
+		}
+	}
+
+	return collector
+}
+
+func help(name string) string {
+	if description, ok := descriptions[name]; ok {
+		return description.help
+	}
+
+	return "SignalR client metric " + name + "."
+}
+
+func buckets(name string) []float64 {
+	if description, ok := descriptions[name]; ok && description.buckets != nil {
+		return description.buckets
+	}
+
+	return prometheus.DefBuckets
+}
+
+func labelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
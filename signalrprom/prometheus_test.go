@@ -0,0 +1,92 @@
+package signalrprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	//Assemble
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+
+	//Act
+	m.IncCounter("signalr_frames_total", map[string]string{"direction": "in"}, 1)
+	m.IncCounter("signalr_frames_total", map[string]string{"direction": "in"}, 2)
+	m.SetGauge("signalr_pending_invocations", nil, 4)
+	m.ObserveHistogram("signalr_invocation_duration_seconds", map[string]string{"hub": "c2", "method": "uE"}, 0.2)
+
+	//Assert
+	if got := testutil.ToFloat64(m.counters["signalr_frames_total"].WithLabelValues("in")); got != 3 {
+		t.Errorf("expected 3 frames in, found %v", got)
+	}
+
+	if got := testutil.ToFloat64(m.gauges["signalr_pending_invocations"]); got != 4 {
+		t.Errorf("expected 4 pending invocations, found %v", got)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unable to gather: %v", err)
+	}
+
+	if len(families) != 3 {
+		t.Errorf("expected 3 registered metrics, found %d", len(families))
+	}
+}
+
+func TestStateDurationBuckets(t *testing.T) {
+	//Assemble
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+
+	//Act
+	m.ObserveHistogram("signalr_state_duration_seconds", map[string]string{"state": "Connected"}, 2*60*60)
+
+	//Assert
+	families, err := registry.Gather()
+	if err != nil || len(families) != 1 {
+		t.Fatalf("unable to gather the state durations: %v", err)
+	}
+
+	if help := families[0].GetHelp(); !strings.Contains(help, "spent in a state") {
+		t.Errorf("expected the metric's own help, found %q", help)
+	}
+
+	histogram := families[0].GetMetric()[0].GetHistogram()
+	buckets := histogram.GetBucket()
+	if upper := buckets[len(buckets)-1].GetUpperBound(); upper < 12*60*60 {
+		t.Errorf("expected buckets reaching hours, found a top bound of %vs", upper)
+	}
+
+	//the two hours belong in a finite bucket, not just +Inf.
+	if cumulative := buckets[len(buckets)-1].GetCumulativeCount(); cumulative != 1 {
+		t.Errorf("expected the observation within the buckets, found %d", cumulative)
+	}
+}
+
+func TestMetricsShareRegistry(t *testing.T) {
+	//Assemble
+	registry := prometheus.NewRegistry()
+	first, second := New(registry), New(registry)
+
+	//Act
+	first.IncCounter("signalr_connects_total", map[string]string{"kind": "connect"}, 1)
+	second.IncCounter("signalr_connects_total", map[string]string{"kind": "connect"}, 1)
+
+	//Assert
+	if got := testutil.ToFloat64(first.counters["signalr_connects_total"].WithLabelValues("connect")); got != 2 {
+		t.Errorf("expected clients sharing a registry to share the counter, found %v", got)
+	}
+}
+
+func TestMetricsIgnoresMismatchedLabels(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.IncCounter("signalr_dropped_total", map[string]string{"kind": "errors"}, 1)
+	//must not panic.
+	m.IncCounter("signalr_dropped_total", map[string]string{"other": "x"}, 1)
+}
@@ -0,0 +1,170 @@
+package signalrtest
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// hubMessage a server push, as carried in a frame's M array.
+type hubMessage struct {
+	Hub       string        `json:"H"`
+	Method    string        `json:"M"`
+	Arguments []interface{} `json:"A"`
+}
+
+// hubInvocation a client's call of a hub method.
+type hubInvocation struct {
+	Hub        string            `json:"H"`
+	Method     string            `json:"M"`
+	Arguments  []json.RawMessage `json:"A"`
+	Identifier string            `json:"I"`
+}
+
+// invocationResponse the result, or error, of a hubInvocation.
+type invocationResponse struct {
+	Identifier string          `json:"I"`
+	Result     json.RawMessage `json:"R,omitempty"`
+	Error      string          `json:"E,omitempty"`
+}
+
+// messageFrame carries pushed messages, with the cursor of the last one.  S marks the frame opening a connection.
+type messageFrame struct {
+	Cursor      string            `json:"C"`
+	Initialized int               `json:"S,omitempty"`
+	Messages    []json.RawMessage `json:"M"`
+}
+
+type bufferedMessage struct {
+	id   uint64
+	data []byte
+}
+
+// connection a negotiated connection, outliving the sockets attached to it.
+type connection struct {
+	id    string
+	token string
+
+	//mutex guards the socket, writes to it, and the buffer.
+	mutex  sync.Mutex
+	socket *websocket.Conn
+	buffer []bufferedMessage
+}
+
+// attach make socket the connection's live socket, dropping any previous one.  A new connection is sent the opening
+// frame; a reconnected one everything buffered after the message it last saw.
+func (conn *connection) attach(s *Server, socket *websocket.Conn, after uint64, initialize bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	if conn.socket != nil {
+		conn.socket.Close()
+	}
+	conn.socket = socket
+
+	frame := messageFrame{Cursor: messageID(after), Messages: []json.RawMessage{}}
+	if initialize {
+		frame.Initialized = 1
+	}
+
+	for _, msg := range conn.buffer {
+		if msg.id > after {
+			frame.Cursor = messageID(msg.id)
+			frame.Messages = append(frame.Messages, msg.data)
+		}
+	}
+
+	if initialize || len(frame.Messages) > 0 {
+		data, _ := json.Marshal(frame)
+		conn.writeLocked(data)
+	}
+}
+
+// detach close socket if it's still the live one.  nil closes whatever is live.
+func (conn *connection) detach(socket *websocket.Conn) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	if conn.socket != nil && (socket == nil || socket == conn.socket) {
+		conn.socket.Close()
+		conn.socket = nil
+	}
+}
+
+func (conn *connection) attached() bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	return conn.socket != nil
+}
+
+// listen answer invocations arriving on socket until it closes.
+func (conn *connection) listen(s *Server, socket *websocket.Conn) {
+	defer conn.detach(socket)
+
+	//pongs are frames too, so they pay the latency.  answered off the read loop, like the invocations.
+	socket.SetPingHandler(func(appData string) error {
+		go func() {
+			s.delay()
+			socket.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
+		}()
+		return nil
+	})
+
+	for {
+		_, data, err := socket.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var invocation hubInvocation
+		if json.Unmarshal(data, &invocation) != nil {
+			continue
+		}
+
+		//the real server runs invocations concurrently, so a slow method doesn't hold up the rest.
+		go func() {
+			conn.write(s, s.invoke(invocation))
+		}()
+	}
+}
+
+// push buffer msg for replay, and send it if a socket is attached.
+func (conn *connection) push(s *Server, msg bufferedMessage) {
+	s.delay()
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	conn.buffer = append(conn.buffer, msg)
+	if overflow := len(conn.buffer) - s.config.BufferSize; overflow > 0 {
+		conn.buffer = conn.buffer[overflow:]
+	}
+
+	data, _ := json.Marshal(messageFrame{Cursor: messageID(msg.id), Messages: []json.RawMessage{msg.data}})
+	conn.writeLocked(data)
+}
+
+// write send a frame after the configured latency, if a socket is attached.
+func (conn *connection) write(s *Server, data []byte) {
+	s.delay()
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	conn.writeLocked(data)
+}
+
+// writeLocked called with mutex held.  A failed write drops the socket; the client will reconnect.
+func (conn *connection) writeLocked(data []byte) {
+	if conn.socket == nil {
+		return
+	}
+
+	if err := conn.socket.WriteMessage(websocket.TextMessage, data); err != nil {
+		conn.socket.Close()
+		conn.socket = nil
+	}
+}
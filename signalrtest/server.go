@@ -0,0 +1,480 @@
+// Package signalrtest provides an in-process classic (ASP.NET) SignalR server for tests.  It speaks the websocket
+// transport of protocol 1.5: negotiate, connect, reconnect, start, abort, ping and send, with hub methods scripted per
+// test, server pushes that are buffered and replayed on reconnect like the real thing, keepalives, forced disconnects
+// and injected latency.
+//
+//	server := signalrtest.NewServer(signalrtest.Config{})
+//	defer server.Close()
+//	server.Handle("c2", "QueryExchangeState", func(args []json.RawMessage) (interface{}, error) {
+//		return map[string]interface{}{"Nonce": 1}, nil
+//	})
+//	conn := signalr.New(signalr.Config{ConnectionURL: server.URL()})
+//
+// It doesn't depend on the client package, so the client's own tests can use it too.
+package signalrtest
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultPath              = "/signalr"
+	defaultProtocolVersion   = "1.5"
+	defaultKeepAliveTimeout  = 20 * time.Second
+	defaultDisconnectTimeout = 30 * time.Second
+	defaultBufferSize        = 1000
+)
+
+// Config shapes the server's behaviour.  Everything is optional.
+type Config struct {
+	//Path base path of the endpoints.  Defaults to /signalr.
+	Path string
+
+	//ProtocolVersion reported by negotiate.  Defaults to 1.5.
+	ProtocolVersion string
+
+	//KeepAliveTimeout and DisconnectTimeout reported by negotiate.  Default to 20 and 30 seconds.
+	KeepAliveTimeout  time.Duration
+	DisconnectTimeout time.Duration
+
+	//KeepAliveInterval send each connected client an empty keepalive frame this often.  Zero disables; see KeepAlive.
+	KeepAliveInterval time.Duration
+
+	//Latency delay applied to every HTTP response and every frame sent.  See SetLatency to change it mid test.
+	Latency time.Duration
+
+	//BufferSize number of pushed messages kept per connection for replay on reconnect.  Defaults to 1000.
+	BufferSize int
+
+	//TLS serve https and wss, with HTTP/2 offered through ALPN as production servers do.  See Certificate.
+	TLS bool
+
+	//Authorize optional check run on every request.  Returning false answers 401 Unauthorized.
+	Authorize func(r *http.Request) bool
+}
+
+// HubMethod scripted server side hub method.  result is marshaled as the invocation's result; a non-nil err is sent as
+// the invocation's error instead.
+type HubMethod func(args []json.RawMessage) (result interface{}, err error)
+
+// Stats requests the server has served, for asserting on what a client did.
+type Stats struct {
+	Negotiates  int
+	Connects    int
+	Reconnects  int
+	Invocations int
+}
+
+// Server classic SignalR server on an httptest.Server.
+type Server struct {
+	config   Config
+	http     *httptest.Server
+	upgrader websocket.Upgrader
+
+	mutex         sync.Mutex
+	connections   map[string]*connection
+	methods       map[string]HubMethod
+	nextToken     int
+	nextMessageID uint64
+
+	latency int64
+
+	negotiates  uint64
+	connects    uint64
+	reconnects  uint64
+	invocations uint64
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewServer start a server.  Close it when done.
+func NewServer(cfg Config) *Server {
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	cfg.Path = path.Join("/", cfg.Path)
+
+	if cfg.ProtocolVersion == "" {
+		cfg.ProtocolVersion = defaultProtocolVersion
+	}
+
+	if cfg.KeepAliveTimeout == 0 {
+		cfg.KeepAliveTimeout = defaultKeepAliveTimeout
+	}
+
+	if cfg.DisconnectTimeout == 0 {
+		cfg.DisconnectTimeout = defaultDisconnectTimeout
+	}
+
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+
+	s := &Server{
+		config:      cfg,
+		connections: map[string]*connection{},
+		methods:     map[string]HubMethod{},
+		latency:     int64(cfg.Latency),
+		stop:        make(chan struct{}),
+	}
+
+	s.http = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	if cfg.TLS {
+		s.http.EnableHTTP2 = true
+		s.http.StartTLS()
+	} else {
+		s.http.Start()
+	}
+
+	if cfg.KeepAliveInterval > 0 {
+		s.done.Add(1)
+		go s.keepAlive(cfg.KeepAliveInterval)
+	}
+
+	return s
+}
+
+// URL the server's base url, to use as the client's ConnectionURL with the default endpoint paths.
+func (s *Server) URL() *url.URL {
+	u, _ := url.Parse(s.http.URL + s.config.Path)
+	return u
+}
+
+// Certificate the certificate a TLS server presents, for the client's RootCAs.  nil without TLS.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.http.Certificate()
+}
+
+// Close drop every connection and shut the server down.
+func (s *Server) Close() {
+	close(s.stop)
+	s.done.Wait()
+	s.Disconnect()
+	s.http.Close()
+}
+
+// Handle script a hub method.  Hub and method names are case insensitive, as in ASP.NET SignalR.  Invocations of
+// unscripted methods fail the way the real server's do.
+func (s *Server) Handle(hub string, method string, fn HubMethod) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.methods[methodKey(hub, method)] = fn
+}
+
+// Push send a hub message to every connection, as Clients.All.method(args...) would.  Connections whose socket is down
+// get it when they reconnect.
+func (s *Server) Push(hub string, method string, args ...interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	data, err := json.Marshal(hubMessage{Hub: hub, Method: method, Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.nextMessageID++
+	msg := bufferedMessage{id: s.nextMessageID, data: data}
+	connections := s.connectionList()
+	s.mutex.Unlock()
+
+	for _, conn := range connections {
+		conn.push(s, msg)
+	}
+
+	return nil
+}
+
+// KeepAlive send every connected client an empty keepalive frame now.
+func (s *Server) KeepAlive() {
+	s.mutex.Lock()
+	connections := s.connectionList()
+	s.mutex.Unlock()
+
+	for _, conn := range connections {
+		conn.write(s, []byte("{}"))
+	}
+}
+
+// Disconnect drop every socket, as a network failure would.  The server remembers the connections, so clients can
+// reconnect and pick up the messages pushed in the meantime.
+func (s *Server) Disconnect() {
+	s.mutex.Lock()
+	connections := s.connectionList()
+	s.mutex.Unlock()
+
+	for _, conn := range connections {
+		conn.detach(nil)
+	}
+}
+
+// Expire forget every connection and drop its socket, as a server restart would.  Reconnects are refused, and clients
+// must negotiate a new connection.
+func (s *Server) Expire() {
+	s.mutex.Lock()
+	connections := s.connectionList()
+	s.connections = map[string]*connection{}
+	s.mutex.Unlock()
+
+	for _, conn := range connections {
+		conn.detach(nil)
+	}
+}
+
+// SetLatency change the delay applied to every HTTP response and frame sent.
+func (s *Server) SetLatency(latency time.Duration) {
+	atomic.StoreInt64(&s.latency, int64(latency))
+}
+
+// Connected number of connections with a live socket.
+func (s *Server) Connected() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	connected := 0
+	for _, conn := range s.connections {
+		if conn.attached() {
+			connected++
+		}
+	}
+
+	return connected
+}
+
+// Stats requests served so far.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Negotiates:  int(atomic.LoadUint64(&s.negotiates)),
+		Connects:    int(atomic.LoadUint64(&s.connects)),
+		Reconnects:  int(atomic.LoadUint64(&s.reconnects)),
+		Invocations: int(atomic.LoadUint64(&s.invocations)),
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.delay()
+
+	if s.config.Authorize != nil && !s.config.Authorize(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, s.config.Path+"/") {
+	case "negotiate":
+		s.negotiate(w, r)
+	case "connect":
+		s.connect(w, r, false)
+	case "reconnect":
+		s.connect(w, r, true)
+	case "start":
+		s.respond(w, map[string]string{"Response": "started"})
+	case "ping":
+		s.respond(w, map[string]string{"Response": "pong"})
+	case "abort":
+		s.abort(w, r)
+	case "send":
+		s.send(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) negotiate(w http.ResponseWriter, r *http.Request) {
+	atomic.AddUint64(&s.negotiates, 1)
+
+	s.mutex.Lock()
+	s.nextToken++
+	conn := &connection{
+		id:    fmt.Sprintf("connection-%d", s.nextToken),
+		token: fmt.Sprintf("token-%d", s.nextToken),
+	}
+	s.connections[conn.token] = conn
+	s.mutex.Unlock()
+
+	s.respond(w, map[string]interface{}{
+		"Url":                     s.config.Path,
+		"ConnectionToken":         conn.token,
+		"ConnectionId":            conn.id,
+		"KeepAliveTimeout":        s.config.KeepAliveTimeout.Seconds(),
+		"DisconnectTimeout":       s.config.DisconnectTimeout.Seconds(),
+		"ConnectionTimeout":       110.0,
+		"TryWebSockets":           true,
+		"ProtocolVersion":         s.config.ProtocolVersion,
+		"TransportConnectTimeout": 5.0,
+		"LongPollDelay":           0.0,
+	})
+}
+
+// connect attach a websocket to a negotiated connection.  A reconnect replays what was pushed after messageId.
+func (s *Server) connect(w http.ResponseWriter, r *http.Request, reconnect bool) {
+	query := r.URL.Query()
+
+	if query.Get("transport") != "webSockets" {
+		http.Error(w, "Only the webSockets transport is supported", http.StatusBadRequest)
+		return
+	}
+
+	conn := s.connection(query.Get("connectionToken"))
+	if conn == nil {
+		http.Error(w, "Unknown connection", http.StatusNotFound)
+		return
+	}
+
+	socket, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	if reconnect {
+		atomic.AddUint64(&s.reconnects, 1)
+		conn.attach(s, socket, parseMessageID(query.Get("messageId")), false)
+	} else {
+		atomic.AddUint64(&s.connects, 1)
+		conn.attach(s, socket, s.lastMessageID(), true)
+	}
+
+	go conn.listen(s, socket)
+}
+
+func (s *Server) abort(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("connectionToken")
+
+	s.mutex.Lock()
+	conn := s.connections[token]
+	delete(s.connections, token)
+	s.mutex.Unlock()
+
+	if conn != nil {
+		conn.detach(nil)
+	}
+}
+
+// send invoke a hub method posted by a non-websocket transport, answering with the result frame.
+func (s *Server) send(w http.ResponseWriter, r *http.Request) {
+	if s.connection(r.URL.Query().Get("connectionToken")) == nil {
+		http.Error(w, "Unknown connection", http.StatusNotFound)
+		return
+	}
+
+	var invocation hubInvocation
+	if err := json.Unmarshal([]byte(r.PostFormValue("data")), &invocation); err != nil {
+		http.Error(w, "Unable to parse invocation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.invoke(invocation))
+}
+
+// invoke run a hub invocation and build its response frame.
+func (s *Server) invoke(invocation hubInvocation) []byte {
+	atomic.AddUint64(&s.invocations, 1)
+
+	response := invocationResponse{Identifier: invocation.Identifier}
+
+	s.mutex.Lock()
+	fn, ok := s.methods[methodKey(invocation.Hub, invocation.Method)]
+	s.mutex.Unlock()
+
+	if !ok {
+		response.Error = fmt.Sprintf(
+			"'%s' method could not be resolved on hub '%s'.",
+			invocation.Method,
+			invocation.Hub,
+		)
+	} else if result, err := fn(invocation.Arguments); err != nil {
+		response.Error = err.Error()
+	} else if response.Result, err = json.Marshal(result); err != nil {
+		response.Error = err.Error()
+	}
+
+	data, _ := json.Marshal(response)
+
+	return data
+}
+
+func (s *Server) respond(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) keepAlive(interval time.Duration) {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.KeepAlive()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Server) delay() {
+	if latency := time.Duration(atomic.LoadInt64(&s.latency)); latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+func (s *Server) connection(token string) *connection {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.connections[token]
+}
+
+// connectionList snapshot of the connections.  called with mutex held.
+func (s *Server) connectionList() []*connection {
+	connections := make([]*connection, 0, len(s.connections))
+	for _, conn := range s.connections {
+		connections = append(connections, conn)
+	}
+
+	return connections
+}
+
+func (s *Server) lastMessageID() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.nextMessageID
+}
+
+func methodKey(hub string, method string) string {
+	return strings.ToLower(hub) + "." + strings.ToLower(method)
+}
+
+// messageID the cursor sent with a message.
+func messageID(id uint64) string {
+	return "d-" + strconv.FormatUint(id, 10)
+}
+
+// parseMessageID the message number in a cursor, or 0 to replay everything buffered if it isn't one of ours.
+func parseMessageID(cursor string) uint64 {
+	id, err := strconv.ParseUint(strings.TrimPrefix(cursor, "d-"), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
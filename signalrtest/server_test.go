@@ -0,0 +1,175 @@
+package signalrtest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2"
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+// connect a client to server, waiting until it's Connected.  The returned func resets it.
+func connect(t *testing.T, server *signalrtest.Server, cfg signalr.Config) (signalr.Connection, func()) {
+	cfg.ConnectionURL = server.URL()
+	conn := signalr.New(cfg)
+	states, unsubscribe := conn.StateSubscription()
+	defer unsubscribe()
+
+	go conn.Connect([]string{"c2"})
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case state := <-states:
+			if state == signalr.Connected {
+				return conn, conn.Reset
+			}
+		case <-timeout:
+			conn.Reset()
+			t.Fatalf("timeout waiting to connect, in state %s", conn.State())
+		}
+	}
+}
+
+func TestCallHub(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	server.Handle("C2", "Echo", func(args []json.RawMessage) (interface{}, error) {
+		var s string
+		err := json.Unmarshal(args[0], &s)
+		return s, err
+	})
+	server.Handle("c2", "Fail", func(args []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("no such market")
+	})
+
+	conn, reset := connect(t, server, signalr.Config{})
+	defer reset()
+
+	//Act
+	var result string
+	echoErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "echo", Arguments: []interface{}{"hello"}}, &result)
+	failErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "Fail"}, &result)
+	missingErr := conn.CallHub(signalr.CallHubPayload{Hub: "c2", Method: "Missing"}, &result)
+	emptyErr := conn.CallHub(signalr.CallHubPayload{}, &result)
+
+	//Assert
+	if echoErr != nil || result != "hello" {
+		t.Errorf("expected the scripted method's result, found %q, %v", result, echoErr)
+	}
+
+	if _, ok := failErr.(signalr.CallHubError); !ok {
+		t.Errorf("expected a CallHubError from the failing method, found %T: %v", failErr, failErr)
+	}
+
+	if missingErr == nil {
+		t.Error("expected an unscripted method to fail")
+	}
+
+	if _, ok := emptyErr.(signalr.CallHubError); !ok {
+		t.Errorf("expected an invocation without a hub to be rejected, found %T: %v", emptyErr, emptyErr)
+	}
+
+	if stats := server.Stats(); stats.Negotiates != 1 || stats.Connects != 1 || stats.Invocations != 4 {
+		t.Errorf("unexpected server stats %+v", stats)
+	}
+}
+
+func TestPushReplayedAfterDisconnect(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	conn, reset := connect(t, server, signalr.Config{})
+	defer reset()
+	messages, unsubscribe := conn.HubResponseSubscription()
+	defer unsubscribe()
+
+	//Act
+	server.Push("c2", "uE", 1)
+	first := <-messages
+	server.Disconnect()
+	server.Push("c2", "uE", 2)
+
+	//Assert
+	if first.Method != "uE" || string(first.Arguments[0]) != "1" {
+		t.Errorf("unexpected first push %+v", first)
+	}
+
+	select {
+	case second := <-messages:
+		if string(second.Arguments[0]) != "2" {
+			t.Errorf("expected the push missed while disconnected, found %+v", second)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the missed push after reconnecting")
+	}
+
+	if stats := server.Stats(); stats.Negotiates != 1 || stats.Reconnects != 1 {
+		t.Errorf("expected one negotiation and one reconnect, found %+v", stats)
+	}
+}
+
+func TestExpireForcesNewConnection(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{DisconnectTimeout: 2 * time.Second})
+	defer server.Close()
+
+	conn, reset := connect(t, server, signalr.Config{})
+	defer reset()
+	gaps := make(chan signalr.GapDetected, 1)
+	conn.OnGapDetected(func(gap signalr.GapDetected) {
+		select {
+		case gaps <- gap:
+		default:
+		}
+	})
+
+	//Act
+	server.Expire()
+
+	//Assert
+	select {
+	case <-gaps:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the client to negotiate a new connection")
+	}
+
+	if stats := server.Stats(); stats.Negotiates != 2 || server.Connected() != 1 {
+		t.Errorf("expected a second negotiation and a live socket, found %+v with %d connected", stats, server.Connected())
+	}
+}
+
+func TestKeepAliveAndLatency(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	conn, reset := connect(t, server, signalr.Config{PingInterval: 50 * time.Millisecond})
+	defer reset()
+	heartbeats, unsubscribe := conn.HeartbeatEventSubscription()
+	defer unsubscribe()
+
+	//Act
+	server.KeepAlive()
+	server.SetLatency(100 * time.Millisecond)
+	time.Sleep(400 * time.Millisecond)
+
+	//Assert
+	select {
+	case hb := <-heartbeats:
+		if hb.Kind != signalr.HeartbeatKeepAlive {
+			t.Errorf("expected a keepalive heartbeat, found %s", hb.Kind)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout waiting for the keepalive")
+	}
+
+	if latency := conn.Stats().Latency; latency.Samples == 0 || latency.Max < 100*time.Millisecond {
+		t.Errorf("expected the pongs to take the injected latency, found %+v", latency)
+	}
+}
@@ -0,0 +1,169 @@
+package signalr
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionState int representing current state of the SignalR Client
+type ConnectionState int
+
+// SignalR Client State Values
+const (
+	Ready ConnectionState = iota
+	Connecting
+	Reconnecting
+	Connected
+	Disconnected
+	Broken
+)
+
+// String implement Stringer interface
+func (cs ConnectionState) String() string {
+	switch cs {
+	case Ready:
+		return "Ready"
+	case Connecting:
+		return "Connecting"
+	case Reconnecting:
+		return "Reconnecting"
+	case Connected:
+		return "Connected"
+	case Disconnected:
+		return "Disconnected"
+	case Broken:
+		return "Broken"
+	}
+
+	return fmt.Sprintf("ConnectionState(%d)", int(cs))
+}
+
+// transitions the legal moves of the connection state machine.  Broken is terminal until Reset returns the client to Ready.
+var transitions = map[ConnectionState][]ConnectionState{
+	Ready:        {Connecting},
+	Connecting:   {Connected, Disconnected, Broken, Ready},
+	Connected:    {Reconnecting, Disconnected, Broken, Ready},
+	Reconnecting: {Connected, Disconnected, Broken, Ready},
+	Disconnected: {Connecting, Reconnecting, Broken, Ready},
+	Broken:       {Ready},
+}
+
+// canTransition true if the state machine allows moving from one state to the other.
+func canTransition(from ConnectionState, to ConnectionState) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StateChange describes a single connection state transition, and why it happened.
+type StateChange struct {
+	From ConnectionState
+	To   ConnectionState
+	//Cause the error behind the transition, if any.  e.g. the read error that dropped the socket.
+	Cause error
+	At    time.Time
+	//Attempt the dial attempt that produced the change, counting from 1.  zero when not dial related.
+	Attempt int
+}
+
+func (c *client) setState(newState ConnectionState) error {
+	return c.changeState(newState, nil, 0)
+}
+
+// changeState move to newState and publish the StateChange to subscribers.  Illegal transitions are rejected, reported
+// on the error feed, and leave the state untouched.
+func (c *client) changeState(newState ConnectionState, cause error, attempt int) error {
+	c.stateMutex.Lock()
+
+	if !canTransition(c.state, newState) {
+		err := InvalidTransitionError{From: c.state, To: newState}
+		c.stateMutex.Unlock()
+
+		c.sendErr(err)
+		return err
+	}
+
+	change := StateChange{
+		From:    c.state,
+		To:      newState,
+		Cause:   cause,
+		At:      time.Now(),
+		Attempt: attempt,
+	}
+	since := c.stateSince
+	c.state = newState
+	c.stateSince = change.At
+
+	c.measureStateChange(change, since)
+
+	if c.logging() {
+		c.config.Logger.Debug(
+			"signalr state change",
+			"from", change.From.String(),
+			"to", change.To.String(),
+			"cause", cause,
+			"attempt", attempt,
+		)
+	}
+
+	c.stateFeed.publish(change)
+	c.stateMutex.Unlock()
+
+	return nil
+}
+
+func (c *client) State() ConnectionState {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+
+	return c.state
+}
+
+// SubscribeToState get a new channel of state changes.  see StateSubscription to be able to unsubscribe.
+func (c *client) SubscribeToState() <-chan ConnectionState {
+	ch, _ := c.StateSubscription()
+	return ch
+}
+
+// StateSubscription a new, independent state channel.  State changes are dropped and counted once its buffer is full.
+func (c *client) StateSubscription() (<-chan ConnectionState, UnsubscribeFunc) {
+	ch := make(chan ConnectionState, defaultEventBufferSize)
+
+	unsubscribe := c.stateFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			select {
+			case ch <- event.(StateChange).To:
+			default:
+				atomic.AddUint64(&c.dropped.states, 1)
+				c.countDrop("states")
+			}
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
+
+// StateChangeSubscription a new, independent channel of full StateChange events.  Dropped and counted once its buffer is full.
+func (c *client) StateChangeSubscription() (<-chan StateChange, UnsubscribeFunc) {
+	ch := make(chan StateChange, defaultEventBufferSize)
+
+	unsubscribe := c.stateFeed.subscribe(
+		func(event interface{}, done <-chan struct{}) {
+			select {
+			case ch <- event.(StateChange):
+			default:
+				atomic.AddUint64(&c.dropped.states, 1)
+				c.countDrop("states")
+			}
+		},
+		func() { close(ch) },
+	)
+
+	return ch, unsubscribe
+}
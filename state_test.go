@@ -0,0 +1,182 @@
+package signalr
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func nextChange(t *testing.T, changes <-chan StateChange) StateChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for state change")
+	}
+
+	return StateChange{}
+}
+
+func TestStateChangeCarriesCause(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+	changes, unsubscribe := c.StateChangeSubscription()
+	defer unsubscribe()
+
+	readErr := errors.New("socket went away")
+	before := time.Now()
+
+	//Act
+	c.setState(Connecting)
+	c.handleSocketReadErr(readErr)
+
+	//Assert
+	first := nextChange(t, changes)
+	if first.From != Ready || first.To != Connecting || first.Cause != nil {
+		t.Errorf("unexpected first change %+v", first)
+	}
+
+	second := nextChange(t, changes)
+	if second.From != Connecting || second.To != Disconnected || second.Cause != readErr {
+		t.Errorf("unexpected second change %+v", second)
+	}
+
+	if second.At.Before(before) {
+		t.Errorf("change timestamp %s predates the change", second.At)
+	}
+}
+
+func TestStateChangeBrokenByNegotiation(t *testing.T) {
+	//Assemble
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+	c.setState(Connecting)
+	changes, _ := c.StateChangeSubscription()
+
+	//Act
+	_, err := c.negotiate()
+
+	//Assert
+	change := nextChange(t, changes)
+	if change.To != Broken || change.Cause != err {
+		t.Errorf("expected broken change caused by %v, found %+v", err, change)
+	}
+}
+
+func TestStateChangeConnectedAttempt(t *testing.T) {
+	//Assemble
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	c := New(Config{ConnectionURL: serverURL}).(*client)
+	c.setState(Connecting)
+	changes, _ := c.StateChangeSubscription()
+
+	//Act
+	if err := c.dialWebSocket(c.config.ConnectPath, url.Values{}, time.Second, time.Time{}); err != nil {
+		t.Fatalf("unable to dial test server: %v", err)
+	}
+
+	//Assert
+	change := nextChange(t, changes)
+	if change.To != Connected || change.Attempt != 1 {
+		t.Errorf("expected connected on attempt 1, found %+v", change)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	states := []ConnectionState{Ready, Connecting, Reconnecting, Connected, Disconnected, Broken}
+
+	legal := map[ConnectionState]map[ConnectionState]bool{
+		Ready:        {Connecting: true},
+		Connecting:   {Connected: true, Disconnected: true, Broken: true, Ready: true},
+		Connected:    {Reconnecting: true, Disconnected: true, Broken: true, Ready: true},
+		Reconnecting: {Connected: true, Disconnected: true, Broken: true, Ready: true},
+		Disconnected: {Connecting: true, Reconnecting: true, Broken: true, Ready: true},
+		Broken:       {Ready: true},
+	}
+
+	for _, from := range states {
+		for _, to := range states {
+			//Assemble
+			c := New(Config{}).(*client)
+			c.state = from
+			changes, _ := c.StateChangeSubscription()
+
+			//Act
+			err := c.setState(to)
+
+			//Assert
+			if legal[from][to] {
+				if err != nil {
+					t.Errorf("%s -> %s: expected legal transition, received %v", from, to, err)
+				}
+
+				if c.State() != to {
+					t.Errorf("%s -> %s: state is %s", from, to, c.State())
+				}
+
+				if len(changes) != 1 {
+					t.Errorf("%s -> %s: expected one state change published, found %d", from, to, len(changes))
+				}
+				continue
+			}
+
+			ite, ok := err.(InvalidTransitionError)
+			if !ok || ite.From != from || ite.To != to {
+				t.Errorf("%s -> %s: expected InvalidTransitionError, received %v", from, to, err)
+			}
+
+			if c.State() != from {
+				t.Errorf("%s -> %s: rejected transition changed state to %s", from, to, c.State())
+			}
+
+			if len(changes) != 0 {
+				t.Errorf("%s -> %s: rejected transition was published", from, to)
+			}
+		}
+	}
+}
+
+func TestConnectionStateString(t *testing.T) {
+	cases := map[ConnectionState]string{
+		Ready:               "Ready",
+		Connecting:          "Connecting",
+		Reconnecting:        "Reconnecting",
+		Connected:           "Connected",
+		Disconnected:        "Disconnected",
+		Broken:              "Broken",
+		ConnectionState(42): "ConnectionState(42)",
+	}
+
+	for state, expected := range cases {
+		if state.String() != expected {
+			t.Errorf("expected %s, found %s", expected, state.String())
+		}
+	}
+}
+
+func TestConnectRejectsConnectedClient(t *testing.T) {
+	c := New(Config{}).(*client)
+	c.state = Connected
+
+	if _, ok := c.Connect([]string{"c2"}).(InvalidTransitionError); !ok {
+		t.Errorf("expected Connect on a connected client to be rejected")
+	}
+}
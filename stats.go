@@ -0,0 +1,201 @@
+package signalr
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWindow number of round trip samples kept for the percentiles.
+const latencyWindow = 256
+
+// LatencyStats round trip times over the most recent samples.  Zero durations until the first sample arrives.
+type LatencyStats struct {
+	//Samples number of round trips the percentiles are drawn from.
+	Samples int
+	Last    time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+}
+
+// Stats snapshot of connection quality.  Counters run from client creation and survive Reset.
+type Stats struct {
+	Latency LatencyStats
+
+	//MessagesIn and MessagesOut websocket frames received from and sent to the peer.
+	MessagesIn  uint64
+	MessagesOut uint64
+
+	//BytesIn and BytesOut frame payload bytes.  See CompressionStats for what crossed the wire.
+	BytesIn  uint64
+	BytesOut uint64
+
+	//Reconnects dropped sockets the client got back, by reconnecting or renegotiating.
+	Reconnects uint64
+
+	//LastMessageAt when the last frame, keepalives included, arrived.  Zero if none has.
+	LastMessageAt time.Time
+
+	//PendingInvocations CallHub calls still waiting on the peer.
+	PendingInvocations int
+}
+
+// trafficCounters running totals, updated atomically.  kept behind a pointer so the 64-bit fields stay aligned.
+type trafficCounters struct {
+	messagesIn    uint64
+	messagesOut   uint64
+	reconnects    uint64
+	lastMessageAt int64
+	//previousMessageAt arrival of the frame before the last, for HeartbeatEvent.SincePrevious.
+	previousMessageAt int64
+}
+
+// latencySamples ring of the most recent round trip times.
+type latencySamples struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+	next    int
+	last    time.Duration
+
+	//waiting SendPing calls, keyed by the payload of their ping.
+	waiting map[string]chan time.Duration
+}
+
+func (ls *latencySamples) record(rtt time.Duration) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	if len(ls.samples) < latencyWindow {
+		ls.samples = append(ls.samples, rtt)
+	} else {
+		ls.samples[ls.next] = rtt
+	}
+
+	ls.next = (ls.next + 1) % latencyWindow
+	ls.last = rtt
+}
+
+// await register for the pong echoing payload.  forget must be called once done waiting.
+func (ls *latencySamples) await(payload string) <-chan time.Duration {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	if ls.waiting == nil {
+		ls.waiting = map[string]chan time.Duration{}
+	}
+
+	pong := make(chan time.Duration, 1)
+	ls.waiting[payload] = pong
+
+	return pong
+}
+
+func (ls *latencySamples) forget(payload string) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	delete(ls.waiting, payload)
+}
+
+// pong hand rtt to the SendPing waiting on payload, if any.
+func (ls *latencySamples) pong(payload string, rtt time.Duration) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	if pong, ok := ls.waiting[payload]; ok {
+		delete(ls.waiting, payload)
+		pong <- rtt
+	}
+}
+
+func (ls *latencySamples) stats() LatencyStats {
+	ls.mutex.Lock()
+	sorted := make([]time.Duration, len(ls.samples))
+	copy(sorted, ls.samples)
+	last := ls.last
+	ls.mutex.Unlock()
+
+	if len(sorted) == 0 {
+		return LatencyStats{}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return LatencyStats{
+		Samples: len(sorted),
+		Last:    last,
+		Min:     sorted[0],
+		Max:     sorted[len(sorted)-1],
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+	}
+}
+
+// percentile nearest-rank percentile of an ascending, non-empty slice.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// pingPayload stamp a websocket ping with its send time, so the pong can be timed.
+func pingPayload() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+// recordPong time a pong against the ping it echoes.  pongs the client didn't stamp are ignored.
+func (c *client) recordPong(appData string) {
+	sent, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		return
+	}
+
+	rtt := time.Since(time.Unix(0, sent))
+	c.latency.record(rtt)
+	c.latency.pong(appData, rtt)
+}
+
+// recordMessageIn count a received frame.
+func (c *client) recordMessageIn(size int) {
+	atomic.AddUint64(&c.bytes.payloadIn, uint64(size))
+	atomic.AddUint64(&c.traffic.messagesIn, 1)
+	previous := atomic.SwapInt64(&c.traffic.lastMessageAt, time.Now().UnixNano())
+	atomic.StoreInt64(&c.traffic.previousMessageAt, previous)
+	c.countFrame("in", size)
+}
+
+// pendingInvocations CallHub calls waiting on a response channel.
+func (c *client) pendingInvocations() int {
+	c.responseChannelMutex.RLock()
+	defer c.responseChannelMutex.RUnlock()
+
+	return len(c.responseChannels)
+}
+
+// Stats snapshot of latency, traffic and reconnects since the client was created.
+func (c *client) Stats() Stats {
+	stats := Stats{
+		Latency:            c.latency.stats(),
+		MessagesIn:         atomic.LoadUint64(&c.traffic.messagesIn),
+		MessagesOut:        atomic.LoadUint64(&c.traffic.messagesOut),
+		BytesIn:            atomic.LoadUint64(&c.bytes.payloadIn),
+		BytesOut:           atomic.LoadUint64(&c.bytes.payloadOut),
+		Reconnects:         atomic.LoadUint64(&c.traffic.reconnects),
+		PendingInvocations: c.pendingInvocations(),
+	}
+
+	if last := atomic.LoadInt64(&c.traffic.lastMessageAt); last != 0 {
+		stats.LastMessageAt = time.Unix(0, last)
+	}
+
+	return stats
+}
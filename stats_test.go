@@ -0,0 +1,147 @@
+package signalr
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/techviking/signalr/v2/signalrtest"
+)
+
+func TestLatencyStats(t *testing.T) {
+	//Assemble
+	samples := &latencySamples{}
+
+	//Act
+	for i := 100; i >= 1; i-- {
+		samples.record(time.Duration(i) * time.Millisecond)
+	}
+
+	//Assert
+	expected := LatencyStats{
+		Samples: 100,
+		Last:    time.Millisecond,
+		Min:     time.Millisecond,
+		Max:     100 * time.Millisecond,
+		P50:     50 * time.Millisecond,
+		P90:     90 * time.Millisecond,
+		P99:     99 * time.Millisecond,
+	}
+
+	if got := samples.stats(); got != expected {
+		t.Errorf("expected %+v, found %+v", expected, got)
+	}
+}
+
+func TestLatencyWindowKeepsRecentSamples(t *testing.T) {
+	//Assemble
+	samples := &latencySamples{}
+
+	//Act
+	for i := 0; i < latencyWindow; i++ {
+		samples.record(time.Hour)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		samples.record(time.Millisecond)
+	}
+
+	//Assert
+	if got := samples.stats(); got.Samples != latencyWindow || got.Max != time.Millisecond {
+		t.Errorf("expected old samples to be evicted, found %+v", got)
+	}
+}
+
+func TestStatsCountsTrafficAndPongs(t *testing.T) {
+	//Assemble
+	c, closeServer := dialPeerServer(t, Config{PingInterval: 20 * time.Millisecond}, peerConfig{})
+	defer closeServer()
+
+	go c.listenToWebSocketData(time.Second)
+	defer c.Reset()
+
+	c.setResponseChan("42")
+	defer c.delResponseChan("42")
+
+	//Act
+	if err := c.sendHubMessage([]byte(`{"H":"c2","M":"SubscribeToExchangeDeltas","A":["BTC-ETH"],"I":"42"}`)); err != nil {
+		t.Fatalf("unable to send: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for c.Stats().Latency.Samples < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("no latency samples from pongs: %+v", c.Stats())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	//Assert
+	stats := c.Stats()
+
+	if stats.MessagesOut != 1 || stats.BytesOut == 0 {
+		t.Errorf("expected one outbound message, found %+v", stats)
+	}
+
+	if stats.PendingInvocations != 1 {
+		t.Errorf("expected one pending invocation, found %d", stats.PendingInvocations)
+	}
+
+	if stats.Latency.Min <= 0 || stats.Latency.Max > time.Second {
+		t.Errorf("implausible round trip times %+v", stats.Latency)
+	}
+}
+
+func TestStatsLastMessageAt(t *testing.T) {
+	//Assemble
+	c := New(Config{}).(*client)
+
+	if !c.Stats().LastMessageAt.IsZero() {
+		t.Fatal("expected no last message before any arrive")
+	}
+
+	//Act
+	before := time.Now()
+	c.recordMessageIn(10)
+
+	//Assert
+	stats := c.Stats()
+	if stats.LastMessageAt.Before(before) || stats.MessagesIn != 1 || stats.BytesIn != 10 {
+		t.Errorf("unexpected stats after one message %+v", stats)
+	}
+}
+
+func TestSendPing(t *testing.T) {
+	//Assemble
+	server := signalrtest.NewServer(signalrtest.Config{})
+	defer server.Close()
+
+	c := New(Config{ConnectionURL: server.URL()}).(*client)
+	states := c.SubscribeToState()
+
+	if _, err := c.SendPing(); err == nil {
+		t.Fatal("expected a ping without a socket to fail")
+	} else if _, ok := err.(SocketError); !ok {
+		t.Errorf("expected a SocketError without a socket, found %T: %v", err, err)
+	}
+
+	go c.Connect([]string{"c2"})
+	defer c.Reset()
+	waitForState(t, states, Connected)
+
+	//Act
+	server.SetLatency(100 * time.Millisecond)
+	rtt, err := c.SendPing()
+
+	//Assert
+	if err != nil || rtt < 100*time.Millisecond {
+		t.Errorf("expected the ping to take the injected latency, found %s, %v", rtt, err)
+	}
+
+	if latency := c.Stats().Latency; latency.Samples != 1 || latency.Last != rtt {
+		t.Errorf("expected the round trip in Stats, found %+v", latency)
+	}
+
+	if invocations := server.Stats().Invocations; invocations != 0 {
+		t.Errorf("expected the ping to stay off the hub, found %d invocations", invocations)
+	}
+}
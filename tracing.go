@@ -0,0 +1,70 @@
+package signalr
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer starts spans around negotiation, dial attempts and hub invocations.  Shaped so an OpenTelemetry adapter is a
+// thin wrapper around a trace.Tracer and a propagator.
+type Tracer interface {
+	//Start begin a span called name as a child of any span in ctx, returning a context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+	//Inject write the trace context carried by ctx into header (e.g. traceparent), for servers that pick it up.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span a single traced operation.
+type Span interface {
+	SetAttribute(key string, value string)
+	//RecordError mark the span failed with err, one of the types in errors.go.
+	RecordError(err error)
+	End()
+}
+
+// Span names and attribute keys
+const (
+	SpanNegotiate = "signalr.negotiate"
+	SpanDial      = "signalr.dial"
+	SpanInvoke    = "signalr.invoke"
+
+	AttributeHub          = "signalr.hub"
+	AttributeMethod       = "signalr.method"
+	AttributeInvocationID = "signalr.invocation_id"
+	AttributeEndpoint     = "signalr.endpoint"
+	AttributeAttempt      = "signalr.attempt"
+	AttributeErrorType    = "signalr.error_type"
+)
+
+// noopSpan stands in when no Tracer is configured.
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value string) {}
+func (noopSpan) RecordError(err error)                 {}
+func (noopSpan) End()                                  {}
+
+// startSpan begin a span under the session context.  Without a Tracer, the session context and a no-op span.
+func (c *client) startSpan(name string) (context.Context, Span) {
+	if c.config.Tracer == nil {
+		return c.context(), noopSpan{}
+	}
+
+	return c.config.Tracer.Start(c.context(), name)
+}
+
+// endSpan record err, if any, and end span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.SetAttribute(AttributeErrorType, errorType(err))
+		span.RecordError(err)
+	}
+
+	span.End()
+}
+
+// injectTrace propagate the trace context of ctx through header.
+func (c *client) injectTrace(ctx context.Context, header http.Header) {
+	if c.config.Tracer != nil {
+		c.config.Tracer.Inject(ctx, header)
+	}
+}
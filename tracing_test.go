@@ -0,0 +1,180 @@
+package signalr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]string
+	errs       []error
+	ended      bool
+}
+
+type spanKey struct{}
+
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	span := &recordedSpan{name: name, attributes: map[string]string{}}
+	rt.spans = append(rt.spans, span)
+
+	return context.WithValue(ctx, spanKey{}, name), &tracedSpan{rt, span}
+}
+
+func (rt *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if name, ok := ctx.Value(spanKey{}).(string); ok {
+		header.Set("traceparent", name)
+	}
+}
+
+// find the first span called name.
+func (rt *recordingTracer) find(name string) *recordedSpan {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	for _, span := range rt.spans {
+		if span.name == name {
+			return span
+		}
+	}
+
+	return nil
+}
+
+type tracedSpan struct {
+	tracer *recordingTracer
+	span   *recordedSpan
+}
+
+func (ts *tracedSpan) SetAttribute(key string, value string) {
+	ts.tracer.mutex.Lock()
+	defer ts.tracer.mutex.Unlock()
+	ts.span.attributes[key] = value
+}
+
+func (ts *tracedSpan) RecordError(err error) {
+	ts.tracer.mutex.Lock()
+	defer ts.tracer.mutex.Unlock()
+	ts.span.errs = append(ts.span.errs, err)
+}
+
+func (ts *tracedSpan) End() {
+	ts.tracer.mutex.Lock()
+	defer ts.tracer.mutex.Unlock()
+	ts.span.ended = true
+}
+
+func TestTraceNegotiatePropagatesContext(t *testing.T) {
+	//Assemble
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"ConnectionToken":"abc","ProtocolVersion":"1.5","KeepAliveTimeout":20}`))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	tracer := &recordingTracer{}
+	c := New(Config{ConnectionURL: serverURL, Tracer: tracer}).(*client)
+
+	//Act
+	if _, err := c.negotiate(); err != nil {
+		t.Fatalf("unexpected negotiate error: %v", err)
+	}
+
+	//Assert
+	span := tracer.find(SpanNegotiate)
+	if span == nil || !span.ended || len(span.errs) != 0 {
+		t.Fatalf("expected an ended, error free negotiate span, found %+v", span)
+	}
+
+	if traceparent != SpanNegotiate {
+		t.Errorf("expected the negotiate span to be propagated in the request headers, found %q", traceparent)
+	}
+}
+
+func TestTraceDialRecordsError(t *testing.T) {
+	//Assemble
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	tracer := &recordingTracer{}
+	c := New(Config{ConnectionURL: serverURL, Tracer: tracer}).(*client)
+
+	//Act
+	_, err := c.dialOnce(&websocket.Dialer{}, c.config.ConnectPath, url.Values{}, 3)
+
+	//Assert
+	if err == nil {
+		t.Fatal("expected the dial to fail")
+	}
+
+	span := tracer.find(SpanDial)
+	if span == nil || !span.ended || len(span.errs) != 1 {
+		t.Fatalf("expected an ended dial span with the error, found %+v", span)
+	}
+
+	if span.attributes[AttributeEndpoint] != c.config.ConnectPath || span.attributes[AttributeAttempt] != "3" {
+		t.Errorf("unexpected dial attributes %v", span.attributes)
+	}
+
+	if traceparent != SpanDial {
+		t.Errorf("expected the dial span to be propagated in the request headers, found %q", traceparent)
+	}
+}
+
+func TestTraceInvocation(t *testing.T) {
+	//Assemble
+	tracer := &recordingTracer{}
+	c := New(Config{Tracer: tracer}).(*client)
+
+	//Act
+	err := c.CallHub(CallHubPayload{Hub: "c2", Method: "QueryExchangeState"}, nil)
+
+	//Assert
+	if err == nil {
+		t.Fatal("expected CallHub without a socket to fail")
+	}
+
+	span := tracer.find(SpanInvoke)
+	if span == nil || !span.ended {
+		t.Fatalf("expected an ended invoke span, found %+v", span)
+	}
+
+	expected := map[string]string{
+		AttributeHub:          "c2",
+		AttributeMethod:       "QueryExchangeState",
+		AttributeInvocationID: fmt.Sprintf("%d", c.nextID),
+		AttributeErrorType:    "SocketError",
+	}
+
+	for key, want := range expected {
+		if got := span.attributes[key]; got != want {
+			t.Errorf("expected %s = %s, found %s", key, want, got)
+		}
+	}
+
+	if len(span.errs) != 1 || span.errs[0] != err {
+		t.Errorf("expected the CallHub error on the span, found %v", span.errs)
+	}
+}
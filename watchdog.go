@@ -0,0 +1,128 @@
+package signalr
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultKeepAliveTimeout  = 20 * time.Second
+	defaultDisconnectTimeout = 30 * time.Second
+
+	pingWriteTimeout = 10 * time.Second
+	pongTimeout      = 10 * time.Second
+)
+
+// seconds convert a negotiated timeout, which the peer sends as fractional seconds.
+func seconds(value float32) time.Duration {
+	return time.Duration(float64(value) * float64(time.Second))
+}
+
+// keepAliveTimeout how long the peer may stay silent before the socket is considered dead.  The negotiated value wins
+// unless it's missing or nonsensical, in which case the configured override (or 20 seconds) is used.
+func (c *client) keepAliveTimeout(nResp *negotiationResponse) time.Duration {
+	if timeout := seconds(nResp.KeepAliveTimeout); timeout >= time.Second {
+		return timeout
+	}
+
+	if c.config.KeepAliveTimeout > 0 {
+		return c.config.KeepAliveTimeout
+	}
+
+	return defaultKeepAliveTimeout
+}
+
+// disconnectTimeout how long after losing the socket the peer still remembers the connection, and so how long it's worth
+// trying to reconnect.  Falls back the same way as keepAliveTimeout, to the configured override or 30 seconds.
+func (c *client) disconnectTimeout(nResp *negotiationResponse) time.Duration {
+	if timeout := seconds(nResp.DisconnectTimeout); timeout >= time.Second {
+		return timeout
+	}
+
+	if c.config.DisconnectTimeout > 0 {
+		return c.config.DisconnectTimeout
+	}
+
+	return defaultDisconnectTimeout
+}
+
+// watchdog tracks peer activity on one socket: it warns with ConnectionSlow once 2/3 of the keepalive timeout passes in
+// silence, pushes the read deadline out on every frame or pong, and sends pings if PingInterval is set.  Pings are stamped
+// with their send time, so every pong is also a latency sample.
+type watchdog struct {
+	c       *client
+	socket  *websocket.Conn
+	timeout time.Duration
+	slow    *time.Timer
+	done    chan struct{}
+}
+
+// startWatchdog begin watching socket.  stop must be called once the read loop is done with it.
+func (c *client) startWatchdog(socket *websocket.Conn, timeout time.Duration) *watchdog {
+	w := &watchdog{
+		c:       c,
+		socket:  socket,
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+
+	w.slow = time.AfterFunc(w.slowAfter(), w.warn)
+	w.alive()
+
+	socket.SetPongHandler(func(appData string) error {
+		w.alive()
+		c.recordPong(appData)
+		return nil
+	})
+
+	if c.config.PingInterval > 0 {
+		go w.ping(c.config.PingInterval)
+	}
+
+	return w
+}
+
+func (w *watchdog) slowAfter() time.Duration {
+	return w.timeout * 2 / 3
+}
+
+// alive record activity from the peer.
+func (w *watchdog) alive() {
+	w.socket.SetReadDeadline(time.Now().Add(w.timeout))
+	w.slow.Reset(w.slowAfter())
+}
+
+func (w *watchdog) warn() {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	w.c.sendErr(ConnectionSlow{
+		Silence:          w.slowAfter(),
+		KeepAliveTimeout: w.timeout,
+	})
+}
+
+func (w *watchdog) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.socket.WriteControl(websocket.PingMessage, pingPayload(), time.Now().Add(pingWriteTimeout)); err != nil {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *watchdog) stop() {
+	close(w.done)
+	w.slow.Stop()
+}
@@ -0,0 +1,131 @@
+package signalr
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNegotiatedTimeoutFallbacks(t *testing.T) {
+	cases := []struct {
+		name       string
+		config     Config
+		negotiated float32
+		expected   time.Duration
+	}{
+		{"negotiated", Config{KeepAliveTimeout: time.Minute}, 12.5, 12500 * time.Millisecond},
+		{"zero uses override", Config{KeepAliveTimeout: time.Minute}, 0, time.Minute},
+		{"bogus uses override", Config{KeepAliveTimeout: time.Minute}, 0.001, time.Minute},
+		{"zero uses default", Config{}, 0, defaultKeepAliveTimeout},
+	}
+
+	for _, tc := range cases {
+		c := New(tc.config).(*client)
+
+		if got := c.keepAliveTimeout(&negotiationResponse{KeepAliveTimeout: tc.negotiated}); got != tc.expected {
+			t.Errorf("%s: expected keepalive timeout %s, found %s", tc.name, tc.expected, got)
+		}
+
+		tc.config.DisconnectTimeout = tc.config.KeepAliveTimeout
+		c = New(tc.config).(*client)
+		expected := tc.expected
+		if expected == defaultKeepAliveTimeout {
+			expected = defaultDisconnectTimeout
+		}
+
+		if got := c.disconnectTimeout(&negotiationResponse{DisconnectTimeout: tc.negotiated}); got != expected {
+			t.Errorf("%s: expected disconnect timeout %s, found %s", tc.name, expected, got)
+		}
+	}
+}
+
+func TestConnectionSlowThenTimeout(t *testing.T) {
+	//Assemble
+	c, closeServer := dialPeerServer(t, Config{}, peerConfig{})
+	defer closeServer()
+
+	errs := c.ListenToErrors()
+	socket := c.currentSocket()
+	start := time.Now()
+
+	//Act
+	done := make(chan error, 1)
+	go func() { done <- c.listenToWebSocketData(300 * time.Millisecond) }()
+
+	//Assert
+	select {
+	case err := <-errs:
+		slow, ok := err.(ConnectionSlow)
+		if !ok {
+			t.Fatalf("expected ConnectionSlow first, received %T: %v", err, err)
+		}
+		if slow.Silence != 200*time.Millisecond {
+			t.Errorf("expected warning at 2/3 of the timeout, found %s", slow.Silence)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for ConnectionSlow")
+	}
+
+	select {
+	case <-done:
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Errorf("read loop ended before the keepalive timeout, after %s", elapsed)
+		}
+		if c.currentSocket() != nil {
+			t.Error("expected the timed out socket to be forgotten")
+		}
+		if err := socket.WriteMessage(websocket.TextMessage, []byte("{}")); err == nil {
+			t.Error("expected the timed out socket to be closed before redialing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the keepalive timeout to end the read loop")
+	}
+}
+
+func TestPongsKeepSocketAlive(t *testing.T) {
+	//Assemble
+	c, closeServer := dialPeerServer(t, Config{PingInterval: 50 * time.Millisecond}, peerConfig{})
+	defer closeServer()
+
+	errs := c.ListenToErrors()
+
+	//Act
+	done := make(chan error, 1)
+	go func() { done <- c.listenToWebSocketData(300 * time.Millisecond) }()
+
+	//Assert
+	select {
+	case err := <-done:
+		t.Fatalf("silent socket with answered pings timed out: %v", err)
+	case err := <-errs:
+		t.Fatalf("unexpected error %T: %v", err, err)
+	case <-time.After(time.Second):
+	}
+
+	c.Reset()
+}
+
+func TestDialGivesUpAtDeadline(t *testing.T) {
+	//Assemble
+	c := New(Config{ConnectionURL: &url.URL{Scheme: "http", Host: "127.0.0.1:1"}}).(*client)
+	c.setState(Connecting)
+
+	//Act
+	start := time.Now()
+	err := c.dialWebSocket(c.config.ReconnectPath, url.Values{}, time.Second, start.Add(1500*time.Millisecond))
+
+	//Assert
+	if _, ok := err.(SocketConnectionError); !ok {
+		t.Errorf("expected SocketConnectionError, received %T: %v", err, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected dialing to stop at the deadline, took %s", elapsed)
+	}
+
+	if c.State() != Connecting {
+		t.Errorf("expected the caller to decide the state, found %s", c.State())
+	}
+}